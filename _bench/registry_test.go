@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthdm/hollywood/actor"
+)
+
+// The registry benchmarks hit the registry of a single engine from all the
+// CPUs: sends look up their target, requests add and remove a response and
+// spawns add and remove an actor. Run them with -cpu 1,4,8,16 to compare the
+// contention on the registry as the number of CPUs grows:
+//
+//	go test -bench=Registry -run=NONE -cpu 1,4,8,16 ./_bench
+
+func newRegistryEngine(b *testing.B, actors int) (*actor.Engine, []*actor.PID) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	if err != nil {
+		b.Fatal(err)
+	}
+	pids := make([]*actor.PID, actors)
+	for i := range pids {
+		pids[i] = e.SpawnFunc(func(c *actor.Context) {
+			if _, ok := c.Message().(int); ok && c.Sender() != nil {
+				c.Respond(c.Message())
+			}
+		}, "echo", actor.WithID(strconv.Itoa(i)))
+	}
+	return e, pids
+}
+
+func BenchmarkRegistrySend(b *testing.B) {
+	e, pids := newRegistryEngine(b, 1024)
+	var seed atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(seed.Add(1)) * 997
+		for pb.Next() {
+			e.Send(pids[i%len(pids)], i)
+			i++
+		}
+	})
+}
+
+func BenchmarkRegistryRequest(b *testing.B) {
+	e, pids := newRegistryEngine(b, 1024)
+	var seed atomic.Int64
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(seed.Add(1)) * 997
+		for pb.Next() {
+			if _, err := e.Request(pids[i%len(pids)], i, time.Second).Result(); err != nil {
+				b.Error(err)
+				return
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkRegistrySpawnPoison(b *testing.B) {
+	e, _ := newRegistryEngine(b, 0)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			pid := e.SpawnFunc(func(*actor.Context) {}, "short")
+			<-e.Poison(pid).Done()
+		}
+	})
+}
@@ -0,0 +1,177 @@
+package actor
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time of an engine. It drives the timers of
+// SendAfter and SendRepeat, the timeouts of requests and receive timeouts,
+// the restart delays and the timestamps of the events. Set it with
+// EngineConfig.WithClock, the default is the system clock.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls fn after the given duration, see time.AfterFunc.
+	AfterFunc(d time.Duration, fn func()) ClockTimer
+}
+
+// ClockTimer is a timer started by Clock.AfterFunc, it is implemented by
+// *time.Timer.
+type ClockTimer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) AfterFunc(d time.Duration, fn func()) ClockTimer {
+	return time.AfterFunc(d, fn)
+}
+
+// SystemClock returns the clock of the operating system.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+func isSystemClock(c Clock) bool {
+	_, ok := c.(systemClock)
+	return ok
+}
+
+// VirtualClock is a Clock that only moves forward when Advance is called,
+// which makes the timers of an engine deterministic in tests. The functions
+// of the timers run on the goroutine that calls Advance, in the order of
+// their deadline, and timers with the same deadline in the order they were
+// started.
+type VirtualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    uint64
+	timers virtualTimers
+}
+
+// NewVirtualClock returns a VirtualClock set to the given time.
+func NewVirtualClock(now time.Time) *VirtualClock {
+	return &VirtualClock{now: now}
+}
+
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *VirtualClock) AfterFunc(d time.Duration, fn func()) ClockTimer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &virtualTimer{clock: c, fn: fn, index: -1}
+	c.schedule(t, d)
+	return t
+}
+
+// Advance moves the clock forward by the given duration and runs the timers
+// that expire in the meantime, including the ones they start. The clock is
+// set to the deadline of each timer before it runs.
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	for len(c.timers) > 0 && !c.timers[0].when.After(end) {
+		t := heap.Pop(&c.timers).(*virtualTimer)
+		if t.when.After(c.now) {
+			c.now = t.when
+		}
+		c.mu.Unlock()
+		t.fn()
+		c.mu.Lock()
+	}
+	if end.After(c.now) {
+		c.now = end
+	}
+	c.mu.Unlock()
+}
+
+// Pending returns the number of timers that did not expire yet.
+func (c *VirtualClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// schedule adds the timer to expire after d, c.mu must be held.
+func (c *VirtualClock) schedule(t *virtualTimer, d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	c.seq++
+	t.when = c.now.Add(d)
+	t.seq = c.seq
+	heap.Push(&c.timers, t)
+}
+
+type virtualTimer struct {
+	clock *VirtualClock
+	fn    func()
+	when  time.Time
+	seq   uint64
+	// index is the position in the heap, -1 when the timer is not pending.
+	index int
+}
+
+func (t *virtualTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.stop()
+}
+
+func (t *virtualTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.stop()
+	t.clock.schedule(t, d)
+	return active
+}
+
+// stop removes the timer from the heap, t.clock.mu must be held.
+func (t *virtualTimer) stop() bool {
+	if t.index < 0 {
+		return false
+	}
+	heap.Remove(&t.clock.timers, t.index)
+	return true
+}
+
+// virtualTimers is a heap of timers ordered by deadline and start order.
+type virtualTimers []*virtualTimer
+
+func (h virtualTimers) Len() int { return len(h) }
+
+func (h virtualTimers) Less(i, j int) bool {
+	if h[i].when.Equal(h[j].when) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].when.Before(h[j].when)
+}
+
+func (h virtualTimers) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *virtualTimers) Push(x any) {
+	t := x.(*virtualTimer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *virtualTimers) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*h = old[:len(old)-1]
+	return t
+}
@@ -0,0 +1,154 @@
+package actor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVirtualClock(t *testing.T) {
+	start := time.Unix(1000, 0)
+	clock := NewVirtualClock(start)
+	var fired []time.Duration
+	record := func() {
+		fired = append(fired, clock.Now().Sub(start))
+	}
+	clock.AfterFunc(3*time.Second, record)
+	clock.AfterFunc(time.Second, func() {
+		record()
+		clock.AfterFunc(time.Second, record)
+	})
+	stopped := clock.AfterFunc(2*time.Second, func() { t.Fatal("stopped timer fired") })
+	assert.True(t, stopped.Stop())
+	assert.False(t, stopped.Stop())
+
+	clock.Advance(500 * time.Millisecond)
+	assert.Empty(t, fired)
+	clock.Advance(5 * time.Second)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}, fired)
+	assert.Equal(t, start.Add(5500*time.Millisecond), clock.Now())
+	assert.Equal(t, 0, clock.Pending())
+}
+
+// newSimulation returns an engine that only runs when the test drives it.
+func newSimulation(t *testing.T, seed int64) (*Engine, *VirtualClock, *DeterministicScheduler) {
+	clock := NewVirtualClock(time.Unix(0, 0))
+	sched := NewDeterministicScheduler(seed)
+	e, err := NewEngine(NewEngineConfig().WithClock(clock).WithScheduler(sched))
+	require.NoError(t, err)
+	return e, clock, sched
+}
+
+func TestSimulationTimers(t *testing.T) {
+	e, clock, sched := newSimulation(t, 1)
+	var received []string
+	pid := e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case Started:
+			c.SendAfter(c.PID(), "after", time.Second)
+		case string:
+			received = append(received, msg)
+		}
+	}, "timers")
+	sched.RunUntilIdle()
+	clock.Advance(999 * time.Millisecond)
+	sched.RunUntilIdle()
+	assert.Empty(t, received)
+	clock.Advance(time.Millisecond)
+	sched.RunUntilIdle()
+	assert.Equal(t, []string{"after"}, received)
+
+	resp := e.Request(pid, 1, time.Second)
+	sched.RunUntilIdle()
+	clock.Advance(time.Second)
+	_, err := resp.Result()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestSimulationRestartDelay(t *testing.T) {
+	e, clock, sched := newSimulation(t, 1)
+	var received []int
+	pid := e.SpawnFunc(func(c *Context) {
+		if n, ok := c.Message().(int); ok {
+			if n == 1 && len(received) == 0 {
+				received = append(received, 0)
+				panic("crash")
+			}
+			received = append(received, n)
+		}
+	}, "crasher", WithRestartDelay(time.Second))
+	e.Send(pid, 1)
+	sched.RunUntilIdle()
+	e.Send(pid, 2)
+	sched.RunUntilIdle()
+	// the process is waiting for its restart delay.
+	assert.Equal(t, []int{0}, received)
+	clock.Advance(time.Second)
+	sched.RunUntilIdle()
+	assert.Equal(t, []int{0, 2}, received)
+}
+
+func TestSimulationBackoffResets(t *testing.T) {
+	e, clock, sched := newSimulation(t, 1)
+	var delays []time.Duration
+	SubscribeFunc(e, func(event ActorRestartedEvent) {
+		delays = append(delays, event.Delay)
+	})
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			panic("crash")
+		}
+	}, "crasher", WithMaxRestarts(10), WithRestartDelay(time.Second), WithRestartPolicy(RestartPolicy{
+		Multiplier: 2,
+	}))
+	crash := func() {
+		e.Send(pid, "crash")
+		sched.RunUntilIdle()
+		clock.Advance(delays[len(delays)-1])
+		sched.RunUntilIdle()
+	}
+	sched.RunUntilIdle()
+	crash()
+	crash()
+	crash()
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, delays)
+
+	// running for longer than the last delay resets the backoff.
+	clock.Advance(5 * time.Second)
+	crash()
+	assert.Equal(t, time.Second, delays[3])
+}
+
+func TestSimulationIsReproducible(t *testing.T) {
+	run := func(seed int64) []string {
+		e, _, sched := newSimulation(t, seed)
+		var order []string
+		collector := e.SpawnFunc(func(c *Context) {
+			if msg, ok := c.Message().(string); ok {
+				order = append(order, msg)
+			}
+		}, "collector")
+		for _, name := range []string{"a", "b", "c"} {
+			name := name
+			pid := e.SpawnFunc(func(c *Context) {
+				if n, ok := c.Message().(int); ok {
+					c.Send(collector, name)
+					if n > 1 {
+						c.Send(c.PID(), n-1)
+					}
+				}
+			}, name)
+			e.Send(pid, 5)
+		}
+		sched.RunUntilIdle()
+		return order
+	}
+	order := run(42)
+	assert.Len(t, order, 15)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, order, run(42))
+	}
+}
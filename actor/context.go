@@ -6,6 +6,7 @@ import (
 	"math"
 	"math/rand"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/anthdm/hollywood/safemap"
@@ -23,6 +24,28 @@ type Context struct {
 	parentCtx *Context
 	children  *safemap.SafeMap[string, *PID]
 	context   context.Context
+	// behaviours is the stack of ReceiveFuncs set by Become, the last one
+	// is active. When empty the Receiver is active.
+	behaviours []ReceiveFunc
+	// stash holds the messages deferred by Stash, unstashed holds the
+	// messages that need to be processed again after UnstashAll.
+	stash     []Envelope
+	unstashed []Envelope
+	// receiveTimer is created the first time a receive timeout is set.
+	receiveTimer *receiveTimer
+	// timers holds the pending timers started by SendAfter and SendRepeat.
+	timersMu sync.Mutex
+	timers   map[*Timer]struct{}
+	// span is the SpanContext of the current received message, inherited by
+	// the messages sent while receiving it.
+	span SpanContext
+	// headers are the headers of the current received message.
+	headers map[string]string
+	// seq is the sequence number of the current received message in the
+	// durable inbox, it is handed over to the envelope when it is stashed.
+	seq uint64
+	// send sends the messages of the actor, through its send middleware.
+	send SendFunc
 }
 
 func newContext(ctx context.Context, e *Engine, pid *PID) *Context {
@@ -31,6 +54,7 @@ func newContext(ctx context.Context, e *Engine, pid *PID) *Context {
 		engine:   e,
 		pid:      pid,
 		children: safemap.New[string, *PID](),
+		send:     e.send,
 	}
 }
 
@@ -48,7 +72,41 @@ func (c *Context) Receiver() Receiver {
 // See Engine.Request for information. This is just a helper function doing that
 // calls Request on the underlying Engine. c.Engine().Request().
 func (c *Context) Request(pid *PID, msg any, timeout time.Duration) *Response {
-	return c.engine.Request(pid, msg, timeout)
+	return c.engine.request(pid, Envelope{Msg: msg, Trace: c.span}, timeout, c.send)
+}
+
+// RequestAsync sends the given message to the given PID as a request without
+// blocking. The callback is invoked on the goroutine of the current process,
+// like any other message, once the response arrived or with
+// context.DeadlineExceeded once the timeout is exceeded. The callback is
+// never invoked when the current process stopped before.
+func (c *Context) RequestAsync(pid *PID, msg any, timeout time.Duration, fn func(resp any, err error)) {
+	span := c.span
+	c.Request(pid, msg, timeout).OnComplete(func(resp any, err error) {
+		// the response may be resolved by the timeout on the goroutine of
+		// the timers, which must not block on a full inbox.
+		c.engine.sendLocal(c.pid, Envelope{Msg: continuation{fn: fn, resp: resp, err: err}, Trace: span, noWait: true})
+	})
+}
+
+// PipeTo runs the given function in its own goroutine and sends its result
+// to the current process as a message. When the function panics, the
+// process receives a PipeToError with the recovered value instead. Nothing
+// is sent when the function returns nil.
+func (c *Context) PipeTo(fn func() any) {
+	span := c.span
+	go func() {
+		var result any
+		defer func() {
+			if v := recover(); v != nil {
+				result = PipeToError{Reason: v}
+			}
+			if result != nil {
+				c.engine.sendLocal(c.pid, Envelope{Msg: result, Trace: span})
+			}
+		}()
+		result = fn()
+	}()
 }
 
 // Respond will sent the given message to the sender of the current received message.
@@ -57,13 +115,18 @@ func (c *Context) Respond(msg any) {
 		slog.Warn("context got no sender", "func", "Respond", "pid", c.PID())
 		return
 	}
-	c.engine.Send(c.sender, msg)
+	c.send(c.sender, Envelope{Msg: msg, Trace: c.span})
 }
 
 // SpawnChild will spawn the given Producer as a child of the current Context.
 // If the parent process dies, all the children will be automatically shutdown gracefully.
 // Hence, all children will receive the Stopped message.
+// SpawnChild returns nil when the engine is shutting down.
 func (c *Context) SpawnChild(p Producer, name string, opts ...OptFunc) *PID {
+	if c.engine.isStopping() {
+		slog.Warn("engine is shutting down", "func", "SpawnChild", "pid", c.pid, "name", name)
+		return nil
+	}
 	options := DefaultOpts(p)
 	options.Kind = c.PID().ID + pidSeparator + name
 	for _, opt := range opts {
@@ -94,11 +157,20 @@ func (c *Context) SpawnChildFunc(f func(*Context), name string, opts ...OptFunc)
 // of the message can call Context.Sender() to know
 // the PID of the process that sent this message.
 func (c *Context) Send(pid *PID, msg any) {
-	c.engine.SendWithSender(pid, msg, c.pid)
+	c.send(pid, Envelope{Msg: msg, Sender: c.pid, Trace: c.span})
+}
+
+// SendWithHeaders behaves like Send and attaches the given headers to the
+// message, which the receiver reads with Context.Header. The headers are
+// preserved when the message is sent to a remote.
+func (c *Context) SendWithHeaders(pid *PID, msg any, headers map[string]string) {
+	c.send(pid, Envelope{Msg: msg, Sender: c.pid, Trace: c.span, Headers: headers})
 }
 
 // SendRepeat will send the given message to the given PID each given interval.
 // It will return a SendRepeater struct that can stop the repeating message by calling Stop().
+// The repeater is stopped automatically when the current process stops.
+// See Engine.SendAfter for the resolution and bounds of the interval.
 func (c *Context) SendRepeat(pid *PID, msg any, interval time.Duration) SendRepeater {
 	sr := SendRepeater{
 		engine:   c.engine,
@@ -106,16 +178,151 @@ func (c *Context) SendRepeat(pid *PID, msg any, interval time.Duration) SendRepe
 		target:   pid.CloneVT(),
 		interval: interval,
 		msg:      msg,
-		cancelch: make(chan struct{}, 1),
 	}
-	sr.start()
+	c.trackTimer(sr.start(c.untrackTimer))
 	return sr
 }
 
-// Forward will forward the current received message to the given PID.
-// This will also set the "forwarder" as the sender of the message.
+// SendAfter will send the given message to the given PID after the given duration.
+// The returned Timer can be used to cancel the message before it is sent. The
+// timer is cancelled automatically when the current process stops. See
+// Engine.SendAfter for the resolution and bounds of the duration.
+func (c *Context) SendAfter(pid *PID, msg any, d time.Duration) *Timer {
+	env := Envelope{Msg: msg, Sender: c.pid, Trace: c.span, noWait: true}
+	t := c.engine.timers.newTimer(func() {
+		c.send(pid, env)
+	}, 0, c.untrackTimer)
+	c.trackTimer(t)
+	return c.engine.timers.start(t, d)
+}
+
+func (c *Context) trackTimer(t *Timer) {
+	c.timersMu.Lock()
+	defer c.timersMu.Unlock()
+	if c.timers == nil {
+		c.timers = make(map[*Timer]struct{})
+	}
+	c.timers[t] = struct{}{}
+}
+
+func (c *Context) untrackTimer(t *Timer) {
+	c.timersMu.Lock()
+	defer c.timersMu.Unlock()
+	delete(c.timers, t)
+}
+
+// stopTimers cancels all the timers started by the process.
+func (c *Context) stopTimers() {
+	c.timersMu.Lock()
+	timers := make([]*Timer, 0, len(c.timers))
+	for t := range c.timers {
+		timers = append(timers, t)
+	}
+	c.timersMu.Unlock()
+	for _, t := range timers {
+		t.Stop()
+	}
+}
+
+// Watch will watch the process of the given PID. The current process will
+// receive a Terminated message when the watched process stops, exceeds its
+// max restarts, or does not exist. Remote processes are terminated when
+// their remote becomes unreachable. A remote process that stops while its
+// remote stays reachable is not reported, since its engine doesn't know
+// about the watchers. Its watch is kept until the remote becomes
+// unreachable, or the watcher unwatches it or stops.
+func (c *Context) Watch(pid *PID) {
+	c.engine.Send(c.engine.watchers(), watch{watcher: c.pid, target: pid})
+}
+
+// Unwatch stops watching the process of the given PID.
+func (c *Context) Unwatch(pid *PID) {
+	c.engine.Send(c.engine.watchers(), unwatch{watcher: c.pid, target: pid})
+}
+
+// Become replaces the active behaviour of the actor with the given function.
+// All following messages are received by it instead of the Receiver, until
+// Become or Unbecome is called. Lifecycle messages (Initialized, Started,
+// Stopped) are always received by the Receiver. On restart the Receiver is
+// active again.
+func (c *Context) Become(f ReceiveFunc) {
+	if len(c.behaviours) > 0 {
+		c.behaviours[len(c.behaviours)-1] = f
+		return
+	}
+	c.behaviours = append(c.behaviours, f)
+}
+
+// BecomeStacked pushes the given function on top of the behaviour stack.
+// Unbecome will bring back the previous behaviour.
+func (c *Context) BecomeStacked(f ReceiveFunc) {
+	c.behaviours = append(c.behaviours, f)
+}
+
+// Unbecome pops the active behaviour, activating the previous one. When
+// the stack is empty the Receiver is active again.
+func (c *Context) Unbecome() {
+	if len(c.behaviours) > 0 {
+		c.behaviours = c.behaviours[:len(c.behaviours)-1]
+	}
+}
+
+func (c *Context) behaviour() ReceiveFunc {
+	if len(c.behaviours) > 0 {
+		return c.behaviours[len(c.behaviours)-1]
+	}
+	return c.receiver.Receive
+}
+
+// Stash defers the current received message, together with its sender and headers,
+// until UnstashAll is called. Stashed messages survive restarts, they are
+// delivered again after the actor is restarted.
+func (c *Context) Stash() {
+	c.stash = append(c.stash, Envelope{Msg: c.message, Sender: c.sender, Trace: c.span, Headers: c.headers, seq: c.seq})
+	c.seq = 0
+}
+
+// UnstashAll delivers all the stashed messages again, in the order they were
+// stashed. They are received right after the current message, before any
+// other message waiting in the inbox.
+func (c *Context) UnstashAll() {
+	c.unstashed = append(c.unstashed, c.stash...)
+	c.stash = nil
+}
+
+func (c *Context) takeUnstashed() []Envelope {
+	msgs := c.unstashed
+	c.unstashed = nil
+	return msgs
+}
+
+// takeStash returns the unstashed and the stashed messages, emptying both.
+func (c *Context) takeStash() []Envelope {
+	msgs := append(c.takeUnstashed(), c.stash...)
+	c.stash = nil
+	return msgs
+}
+
+// SetReceiveTimeout makes the actor receive a ReceiveTimeout message each
+// time it did not receive any message for the given duration. A duration
+// of zero disables the receive timeout.
+func (c *Context) SetReceiveTimeout(d time.Duration) {
+	if c.receiveTimer == nil {
+		if d <= 0 {
+			return
+		}
+		c.receiveTimer = newReceiveTimer(c.engine.clock, func() {
+			c.engine.SendLocal(c.pid, ReceiveTimeout{}, nil)
+		})
+	}
+	c.receiveTimer.set(d)
+}
+
+// Forward will forward the current received message, together with its
+// headers, to the given PID. This will also set the "forwarder" as the sender
+// of the message.
 func (c *Context) Forward(pid *PID) {
-	c.engine.SendWithSender(pid, c.message, c.pid)
+	c.send(pid, Envelope{Msg: c.message, Sender: c.pid, Trace: c.span, Headers: c.headers})
 }
 
 // GetPID returns the PID of the process found by the given id.
@@ -145,15 +352,54 @@ func (c *Context) Child(id string) *PID {
 
 // Children returns all child PIDs for the current process.
 func (c *Context) Children() []*PID {
-	pids := make([]*PID, c.children.Len())
-	i := 0
+	pids := make([]*PID, 0, c.children.Len())
 	c.children.ForEach(func(_ string, child *PID) {
-		pids[i] = child
-		i++
+		pids = append(pids, child)
 	})
 	return pids
 }
 
+// siblings returns the PIDs of the other children of our parent.
+func (c *Context) siblings() []*PID {
+	if c.parentCtx == nil {
+		return nil
+	}
+	children := c.parentCtx.Children()
+	siblings := make([]*PID, 0, len(children))
+	for _, pid := range children {
+		if !pid.Equals(c.pid) {
+			siblings = append(siblings, pid)
+		}
+	}
+	return siblings
+}
+
+// SpanContext returns the SpanContext of the current received message. The
+// messages sent while receiving it, with Send, Forward, Respond, Request and
+// the like, carry the same SpanContext.
+func (c *Context) SpanContext() SpanContext {
+	return c.span
+}
+
+// SetSpanContext replaces the SpanContext of the current received message,
+// which is how tracing middleware makes the messages sent by the actor
+// children of its span.
+func (c *Context) SetSpanContext(sc SpanContext) {
+	c.span = sc
+}
+
+// Header returns the value of the header with the given key of the current
+// received message, or an empty string when it has no such header.
+func (c *Context) Header(key string) string {
+	return c.headers[key]
+}
+
+// Headers returns the headers of the current received message, which must not
+// be modified.
+func (c *Context) Headers() map[string]string {
+	return c.headers
+}
+
 // PID returns the PID of the process that belongs to the context.
 func (c *Context) PID() *PID {
 	return c.pid
@@ -1,7 +1,9 @@
 package actor
 
 import (
+	"context"
 	fmt "fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -170,3 +172,344 @@ func TestSpawnChild(t *testing.T) {
 	assert.Nil(t, e.Registry.get(NewPID("local", "child")))
 	assert.Nil(t, e.Registry.get(pid))
 }
+
+func TestBecomeUnbecome(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		received = make(chan string, 10)
+		closed   func(*Context)
+		open     func(*Context)
+	)
+	closed = func(c *Context) {
+		switch msg := c.Message().(type) {
+		case string:
+			received <- "closed:" + msg
+			c.Become(open)
+		}
+	}
+	open = func(c *Context) {
+		switch msg := c.Message().(type) {
+		case string:
+			received <- "open:" + msg
+			c.Unbecome()
+		}
+	}
+	pid := e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case Started:
+			c.BecomeStacked(closed)
+		case string:
+			received <- "receiver:" + msg
+		}
+	}, "door")
+	for _, msg := range []string{"a", "b", "c"} {
+		e.Send(pid, msg)
+	}
+	assert.Equal(t, "closed:a", <-received)
+	assert.Equal(t, "open:b", <-received)
+	assert.Equal(t, "receiver:c", <-received)
+}
+
+func TestStashUnstashAll(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	type loaded struct{}
+	var (
+		received = make(chan int, 10)
+		ready    bool
+	)
+	pid := e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case loaded:
+			ready = true
+			c.UnstashAll()
+		case int:
+			if !ready {
+				c.Stash()
+				return
+			}
+			received <- msg
+		}
+	}, "loader")
+	e.Send(pid, 1)
+	e.Send(pid, 2)
+	e.Send(pid, loaded{})
+	e.Send(pid, 3)
+	for i := 1; i <= 3; i++ {
+		select {
+		case msg := <-received:
+			assert.Equal(t, i, msg)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+}
+
+func TestStashSurvivesRestart(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		received = make(chan int, 10)
+		starts   int
+	)
+	pid := e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case Started:
+			starts++
+		case string:
+			panic("crash")
+		case int:
+			if starts == 1 {
+				c.Stash()
+				return
+			}
+			received <- msg
+		}
+	}, "foo", WithRestartDelay(0))
+	e.Send(pid, 1)
+	e.Send(pid, 2)
+	e.Send(pid, "crash")
+	for i := 1; i <= 2; i++ {
+		select {
+		case msg := <-received:
+			assert.Equal(t, i, msg)
+		case <-time.After(time.Second):
+			t.Fatal("stashed message lost on restart")
+		}
+	}
+}
+
+func TestReceiveTimeout(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		timeouts = make(chan time.Time, 10)
+		start    = time.Now()
+	)
+	pid := e.SpawnFunc(func(c *Context) {
+		switch c.Message().(type) {
+		case ReceiveTimeout:
+			timeouts <- time.Now()
+			c.SetReceiveTimeout(0)
+		}
+	}, "session", WithReceiveTimeout(time.Millisecond*30))
+
+	// keep the actor busy, it should not time out in the meantime.
+	for i := 0; i < 10; i++ {
+		e.Send(pid, i)
+		time.Sleep(time.Millisecond * 10)
+	}
+	select {
+	case at := <-timeouts:
+		assert.True(t, at.Sub(start) >= time.Millisecond*120)
+	case <-time.After(time.Second):
+		t.Fatal("expected ReceiveTimeout")
+	}
+	// the receive timeout is disabled now.
+	select {
+	case <-timeouts:
+		t.Fatal("did not expect another ReceiveTimeout")
+	case <-time.After(time.Millisecond * 60):
+	}
+	<-e.Poison(pid).Done()
+}
+
+func TestRequestAsync(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	type result struct {
+		resp any
+		err  error
+	}
+	var (
+		results = make(chan result, 2)
+		server  = e.SpawnFunc(func(c *Context) {
+			if msg, ok := c.Message().(string); ok {
+				c.Respond(msg + "-pong")
+			}
+		}, "server")
+		silent = e.SpawnFunc(func(c *Context) {}, "silent")
+	)
+	e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(Started); ok {
+			c.RequestAsync(server, "ping", time.Second, func(resp any, err error) {
+				results <- result{resp: resp, err: err}
+			})
+			c.RequestAsync(silent, "ping", time.Millisecond*20, func(resp any, err error) {
+				results <- result{resp: resp, err: err}
+			})
+		}
+	}, "client")
+
+	res := <-results
+	require.NoError(t, res.err)
+	assert.Equal(t, "ping-pong", res.resp)
+	res = <-results
+	assert.ErrorIs(t, res.err, context.DeadlineExceeded)
+	assert.Nil(t, res.resp)
+
+	// both responses are removed from the registry.
+	assert.Eventually(t, func() bool {
+		found := false
+		e.Registry.each(func(id string, _ Processer) {
+			found = found || strings.HasPrefix(id, "response")
+		})
+		return !found
+	}, time.Second, time.Millisecond)
+}
+
+func TestPipeTo(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		wg     sync.WaitGroup
+		result string
+	)
+	wg.Add(1)
+	e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case Started:
+			c.PipeTo(func() any {
+				return "done"
+			})
+		case string:
+			result = msg
+			wg.Done()
+		}
+	}, "foo")
+	wg.Wait()
+	assert.Equal(t, "done", result)
+}
+
+func TestPipeToPanic(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	received := make(chan PipeToError, 1)
+	e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case Started:
+			c.PipeTo(func() any {
+				panic("boom")
+			})
+		case PipeToError:
+			received <- msg
+		}
+	}, "foo")
+	select {
+	case msg := <-received:
+		assert.Equal(t, "boom", msg.Reason)
+	case <-time.After(time.Second):
+		t.Fatal("PipeToError not received")
+	}
+}
+
+func TestPipeToNil(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	received := make(chan any, 1)
+	e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case Started:
+			c.PipeTo(func() any {
+				return nil
+			})
+		case Initialized:
+		default:
+			received <- msg
+		}
+	}, "foo")
+	select {
+	case msg := <-received:
+		t.Fatalf("unexpected message %v", msg)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestSpanContextInherited(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	sc := SpanContext{TraceID: TraceID{1}, SpanID: SpanID{2}}
+	received := make(chan SpanContext, 2)
+	child := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			received <- c.SpanContext()
+		}
+	}, "child")
+	parent := e.SpawnFunc(func(c *Context) {
+		switch c.Message().(type) {
+		case int:
+			received <- c.SpanContext()
+			c.Send(child, "forwarded")
+		case string:
+			received <- c.SpanContext()
+		}
+	}, "parent")
+	e.SendEnvelope(parent, Envelope{Msg: 1, Trace: sc})
+	assert.Equal(t, sc, <-received)
+	assert.Equal(t, sc, <-received)
+
+	// messages sent without trace context carry an empty one.
+	e.Send(parent, "untraced")
+	assert.False(t, (<-received).IsValid())
+}
+
+func TestSendWithHeaders(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	headers := make(chan map[string]string, 2)
+	target := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			headers <- c.Headers()
+		}
+	}, "target")
+	forwarder := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			assert.Equal(t, "acme", c.Header("tenant"))
+			assert.Equal(t, "", c.Header("missing"))
+			c.Forward(target)
+		}
+	}, "forwarder")
+	sender := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(int); ok {
+			c.SendWithHeaders(forwarder, "hello", map[string]string{"tenant": "acme"})
+			c.Send(target, "no headers")
+		}
+	}, "sender")
+	e.Send(sender, 1)
+	received := []map[string]string{<-headers, <-headers}
+	assert.Contains(t, received, map[string]string{"tenant": "acme"})
+	assert.Contains(t, received, map[string]string(nil))
+}
+
+func TestSendMiddleware(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	received := make(chan string, 1)
+	target := e.SpawnFunc(func(c *Context) {
+		if msg, ok := c.Message().(string); ok {
+			received <- msg
+		}
+	}, "target")
+	upper := func(next SendFunc) SendFunc {
+		return func(pid *PID, env Envelope) {
+			env.Msg = strings.ToUpper(env.Msg.(string))
+			next(pid, env)
+		}
+	}
+	drop := func(next SendFunc) SendFunc {
+		return func(pid *PID, env Envelope) {
+			if env.Msg != "DROP" {
+				next(pid, env)
+			}
+		}
+	}
+	sender := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(int); ok {
+			c.Send(target, "drop")
+			c.Send(target, "hello")
+		}
+	}, "sender", WithSendMiddleware(upper, drop))
+	e.Send(sender, 1)
+	assert.Equal(t, "HELLO", <-received)
+}
@@ -0,0 +1,281 @@
+package actor
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"time"
+)
+
+const (
+	defaultDeadLetterHistory     = 1000
+	defaultDeadLetterLogInterval = time.Second
+	// deadLetterStoreTimeout is the timeout of the requests to the store.
+	deadLetterStoreTimeout = time.Second * 5
+	// deadLetterLogTargets is the maximum number of targets logged at once.
+	deadLetterLogTargets = 10
+	// deadLetterMaxCounters is the maximum number of targets counted.
+	deadLetterMaxCounters = 10000
+)
+
+// DeadLetterStoreConfig holds the configuration of the deadletter store.
+type DeadLetterStoreConfig struct {
+	history     int
+	logInterval time.Duration
+}
+
+// NewDeadLetterStoreConfig returns a new default DeadLetterStoreConfig, which
+// keeps the last 1000 deadletters and logs them every second.
+func NewDeadLetterStoreConfig() DeadLetterStoreConfig {
+	return DeadLetterStoreConfig{
+		history:     defaultDeadLetterHistory,
+		logInterval: defaultDeadLetterLogInterval,
+	}
+}
+
+// WithHistory sets the number of deadletters the store keeps.
+func (config DeadLetterStoreConfig) WithHistory(size int) DeadLetterStoreConfig {
+	config.history = size
+	return config
+}
+
+// WithLogInterval sets the interval at which the deadletters are logged,
+// aggregated by target. Zero disables the logging.
+func (config DeadLetterStoreConfig) WithLogInterval(d time.Duration) DeadLetterStoreConfig {
+	config.logInterval = d
+	return config
+}
+
+// DeadLetter is a deadletter kept by the deadletter store.
+type DeadLetter struct {
+	Target    *PID
+	Message   any
+	Sender    *PID
+	Headers   map[string]string
+	Trace     SpanContext
+	Timestamp time.Time
+}
+
+// DeadLetterFilter selects deadletters, the zero value selects all of them.
+type DeadLetterFilter struct {
+	// Target matches the deadletters sent to the given PID.
+	Target *PID
+	// Sender matches the deadletters sent by the given PID.
+	Sender *PID
+	// Type matches the deadletters with a message of the given type.
+	Type reflect.Type
+}
+
+func (f DeadLetterFilter) match(dl DeadLetter) bool {
+	if f.Target != nil && !f.Target.Equals(dl.Target) {
+		return false
+	}
+	if f.Sender != nil && (dl.Sender == nil || !f.Sender.Equals(dl.Sender)) {
+		return false
+	}
+	if f.Type != nil && reflect.TypeOf(dl.Message) != f.Type {
+		return false
+	}
+	return true
+}
+
+// DeadLetterStore gives access to the deadletter store of an engine.
+type DeadLetterStore struct {
+	engine *Engine
+	pid    *PID
+}
+
+type deadLetterQuery struct {
+	filter DeadLetterFilter
+}
+
+type deadLetterCount struct {
+	target *PID
+}
+
+type deadLetterReplay struct {
+	filter DeadLetterFilter
+	target *PID
+}
+
+type deadLetterLog struct{}
+
+// Query returns the deadletters in the history of the store that match the
+// given filter, oldest first.
+func (s *DeadLetterStore) Query(filter DeadLetterFilter) ([]DeadLetter, error) {
+	resp, err := s.engine.Request(s.pid, deadLetterQuery{filter: filter}, deadLetterStoreTimeout).Result()
+	if err != nil {
+		return nil, err
+	}
+	return resp.([]DeadLetter), nil
+}
+
+// Count returns the number of deadletters sent to the given PID since the
+// store was started, including the ones that are not in the history anymore.
+func (s *DeadLetterStore) Count(target *PID) (uint64, error) {
+	resp, err := s.engine.Request(s.pid, deadLetterCount{target: target}, deadLetterStoreTimeout).Result()
+	if err != nil {
+		return 0, err
+	}
+	return resp.(uint64), nil
+}
+
+// Replay sends the deadletters that match the given filter again, to the
+// given target or to their original target if it is nil, keeping their
+// original sender, headers and SpanContext. Replayed deadletters are removed from the history. It
+// returns the number of replayed deadletters.
+func (s *DeadLetterStore) Replay(filter DeadLetterFilter, target *PID) (int, error) {
+	resp, err := s.engine.Request(s.pid, deadLetterReplay{filter: filter, target: target}, deadLetterStoreTimeout).Result()
+	if err != nil {
+		return 0, err
+	}
+	return resp.(int), nil
+}
+
+// DeadLetters returns the deadletter store of the engine, or nil when the
+// engine was not configured with one. See EngineConfig.WithDeadLetterStore.
+func (e *Engine) DeadLetters() *DeadLetterStore {
+	if e.deadLetters == nil {
+		return nil
+	}
+	return &DeadLetterStore{engine: e, pid: e.deadLetters}
+}
+
+// deadLetterStore keeps a bounded history of the DeadLetterEvents broadcasted
+// over the event stream and logs them periodically, aggregated by target.
+type deadLetterStore struct {
+	config  DeadLetterStoreConfig
+	history []DeadLetter
+	// counters holds the number of deadletters per target, at most
+	// deadLetterMaxCounters targets are kept.
+	counters map[pidKey]uint64
+	// pending holds the deadletters per target since the last log.
+	pending map[pidKey]uint64
+}
+
+func newDeadLetterStore(config DeadLetterStoreConfig) Producer {
+	return func() Receiver {
+		return &deadLetterStore{
+			config:   config,
+			counters: make(map[pidKey]uint64),
+			pending:  make(map[pidKey]uint64),
+		}
+	}
+}
+
+func (s *deadLetterStore) Receive(c *Context) {
+	switch msg := c.Message().(type) {
+	case Started:
+		c.engine.Subscribe(c.PID(), EventType[DeadLetterEvent]())
+		if s.config.logInterval > 0 {
+			c.SendRepeat(c.PID(), deadLetterLog{}, s.config.logInterval)
+		}
+	case DeadLetterEvent:
+		s.add(DeadLetter{
+			Target:    msg.Target,
+			Message:   msg.Message,
+			Sender:    msg.Sender,
+			Headers:   msg.Headers,
+			Trace:     msg.Trace,
+			Timestamp: c.engine.clock.Now(),
+		})
+	case deadLetterQuery:
+		dls := []DeadLetter{}
+		for _, dl := range s.entries() {
+			if msg.filter.match(dl) {
+				dls = append(dls, dl)
+			}
+		}
+		c.Respond(dls)
+	case deadLetterCount:
+		c.Respond(s.counters[watchKey(msg.target)])
+	case deadLetterReplay:
+		c.Respond(s.replay(c, msg.filter, msg.target))
+	case deadLetterLog:
+		s.log()
+	}
+}
+
+func (s *deadLetterStore) add(dl DeadLetter) {
+	if s.config.history > 0 {
+		s.history = append(s.history, dl)
+		// trim the history once it doubled, which amortizes the copy.
+		if len(s.history) >= s.config.history*2 {
+			s.history = append(s.history[:0], s.entries()...)
+		}
+	}
+	if dl.Target == nil {
+		return
+	}
+	key := watchKey(dl.Target)
+	if _, ok := s.counters[key]; !ok && len(s.counters) >= deadLetterMaxCounters {
+		// drop a random target to keep the counters bounded.
+		for k := range s.counters {
+			delete(s.counters, k)
+			break
+		}
+	}
+	s.counters[key]++
+	if s.config.logInterval > 0 {
+		s.pending[key]++
+	}
+}
+
+// entries returns the deadletters in the history, oldest first.
+func (s *deadLetterStore) entries() []DeadLetter {
+	if len(s.history) > s.config.history {
+		return s.history[len(s.history)-s.config.history:]
+	}
+	return s.history
+}
+
+func (s *deadLetterStore) replay(c *Context, filter DeadLetterFilter, target *PID) int {
+	var (
+		kept     = s.history[:0]
+		replayed = 0
+	)
+	for _, dl := range s.entries() {
+		if !filter.match(dl) {
+			kept = append(kept, dl)
+			continue
+		}
+		to := target
+		if to == nil {
+			to = dl.Target
+		}
+		c.engine.SendEnvelope(to, Envelope{
+			Msg:     dl.Message,
+			Sender:  dl.Sender,
+			Trace:   dl.Trace,
+			Headers: dl.Headers,
+		})
+		replayed++
+	}
+	s.history = kept
+	return replayed
+}
+
+// log logs the deadletters since the last log, one line per target starting
+// with the target with the most deadletters.
+func (s *deadLetterStore) log() {
+	if len(s.pending) == 0 {
+		return
+	}
+	targets := make([]pidKey, 0, len(s.pending))
+	for key := range s.pending {
+		targets = append(targets, key)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return s.pending[targets[i]] > s.pending[targets[j]]
+	})
+	for i, key := range targets {
+		if i == deadLetterLogTargets {
+			slog.Warn("Deadletters to more targets", "targets", len(targets)-i)
+			break
+		}
+		slog.Warn("Deadletters", "target", fmt.Sprintf("%s/%s", key.address, key.id),
+			"count", s.pending[key], "interval", s.config.logInterval)
+	}
+	s.pending = make(map[pidKey]uint64)
+}
@@ -2,10 +2,15 @@ package actor
 
 import (
 	"bytes"
+	"log/slog"
+	"reflect"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestDeadLetterCustom tests the custom deadletter handling.
@@ -51,3 +56,99 @@ func invalidPid() *PID {
 		ID:      "squirrel",
 	}
 }
+
+func TestDeadLetterStore(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig().WithDeadLetterStore(
+		NewDeadLetterStoreConfig().WithHistory(3).WithLogInterval(0)))
+	require.NoError(t, err)
+	store := e.DeadLetters()
+	require.NotNil(t, store)
+
+	var (
+		foo    = NewPID(LocalLookupAddr, "foo/1")
+		bar    = NewPID(LocalLookupAddr, "bar/1")
+		sender = NewPID(LocalLookupAddr, "sender/1")
+	)
+	e.SendLocal(foo, "a", nil)
+	e.SendLocal(foo, 1, sender)
+	e.SendLocal(bar, "b", nil)
+	e.SendLocal(bar, "c", sender)
+
+	// the history only keeps the last 3 deadletters.
+	require.Eventually(t, func() bool {
+		n, err := store.Count(bar)
+		return err == nil && n == 2
+	}, time.Second, time.Millisecond*10)
+	dls, err := store.Query(DeadLetterFilter{})
+	require.NoError(t, err)
+	require.Len(t, dls, 3)
+	assert.Equal(t, 1, dls[0].Message)
+
+	n, err := store.Count(foo)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), n)
+
+	dls, err = store.Query(DeadLetterFilter{Type: reflect.TypeOf("")})
+	require.NoError(t, err)
+	assert.Len(t, dls, 2)
+	dls, err = store.Query(DeadLetterFilter{Sender: sender, Target: bar})
+	require.NoError(t, err)
+	require.Len(t, dls, 1)
+	assert.Equal(t, "c", dls[0].Message)
+}
+
+func TestDeadLetterStoreReplay(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig().WithDeadLetterStore(NewDeadLetterStoreConfig()))
+	require.NoError(t, err)
+	store := e.DeadLetters()
+	var (
+		foo      = NewPID(LocalLookupAddr, "foo/1")
+		sender   = NewPID(LocalLookupAddr, "sender/1")
+		received = make(chan Envelope, 2)
+	)
+	sc := SpanContext{TraceID: TraceID{1}, SpanID: SpanID{2}}
+	e.SendLocalEnvelope(foo, Envelope{Msg: "a", Sender: sender, Headers: map[string]string{"id": "1"}, Trace: sc})
+	e.SendLocal(foo, 1, sender)
+	require.Eventually(t, func() bool {
+		n, err := store.Count(foo)
+		return err == nil && n == 2
+	}, time.Second, time.Millisecond*10)
+
+	target := e.SpawnFunc(func(c *Context) {
+		if msg, ok := c.Message().(string); ok {
+			received <- Envelope{Msg: msg, Sender: c.Sender(), Headers: c.Headers(), Trace: c.SpanContext()}
+		}
+	}, "target")
+	n, err := store.Replay(DeadLetterFilter{Type: reflect.TypeOf("")}, target)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	env := <-received
+	assert.Equal(t, "a", env.Msg)
+	assert.True(t, env.Sender.Equals(sender))
+	assert.Equal(t, "1", env.Headers["id"])
+	assert.Equal(t, sc, env.Trace)
+
+	// replayed deadletters are removed from the history.
+	dls, err := store.Query(DeadLetterFilter{})
+	require.NoError(t, err)
+	require.Len(t, dls, 1)
+	assert.Equal(t, 1, dls[0].Message)
+}
+
+func TestDeadLetterStoreLog(t *testing.T) {
+	buf := &SafeBuffer{}
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(buf, nil)))
+
+	e, err := NewEngine(NewEngineConfig().WithDeadLetterStore(
+		NewDeadLetterStoreConfig().WithLogInterval(time.Millisecond * 20)))
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		e.SendLocal(invalidPid(), i, nil)
+	}
+	require.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), "Deadletters")
+	}, time.Second, time.Millisecond*10)
+	assert.Equal(t, 1, strings.Count(buf.String(), "Deadletters"))
+	assert.Contains(t, buf.String(), "count=5")
+}
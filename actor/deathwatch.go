@@ -0,0 +1,163 @@
+package actor
+
+// TerminatedReason tells why a watched process is terminated.
+type TerminatedReason int
+
+const (
+	// TerminatedStopped means the watched process was stopped.
+	TerminatedStopped TerminatedReason = iota
+	// TerminatedMaxRestarts means the watched process crashed too many times.
+	TerminatedMaxRestarts
+	// TerminatedNotFound means the watched process did not exist.
+	TerminatedNotFound
+	// TerminatedRemoteUnreachable means the remote hosting the watched
+	// process could not be reached anymore.
+	TerminatedRemoteUnreachable
+)
+
+func (r TerminatedReason) String() string {
+	switch r {
+	case TerminatedStopped:
+		return "stopped"
+	case TerminatedMaxRestarts:
+		return "max restarts exceeded"
+	case TerminatedNotFound:
+		return "not found"
+	case TerminatedRemoteUnreachable:
+		return "remote unreachable"
+	default:
+		return "unknown"
+	}
+}
+
+// Terminated is sent to all the watchers of a process when it terminates.
+// See Context.Watch.
+type Terminated struct {
+	PID    *PID
+	Reason TerminatedReason
+}
+
+type watch struct {
+	watcher *PID
+	target  *PID
+}
+
+type unwatch struct {
+	watcher *PID
+	target  *PID
+}
+
+// deathWatch keeps track of the watched processes and notifies their
+// watchers by listening to the event stream.
+type deathWatch struct {
+	watched map[pidKey]*watchedProc
+	// watching holds the processes each watcher is watching, so we can
+	// clean them up when the watcher itself stops.
+	watching map[pidKey]*PIDSet
+}
+
+type watchedProc struct {
+	pid      *PID
+	watchers *PIDSet
+}
+
+func newDeathWatch() Producer {
+	return func() Receiver {
+		return &deathWatch{
+			watched:  make(map[pidKey]*watchedProc),
+			watching: make(map[pidKey]*PIDSet),
+		}
+	}
+}
+
+func (d *deathWatch) Receive(c *Context) {
+	switch msg := c.Message().(type) {
+	case Started:
+		c.engine.Subscribe(c.PID(),
+			EventType[ActorStoppedEvent](),
+			EventType[ActorMaxRestartsExceededEvent](),
+			EventType[RemoteUnreachableEvent](),
+		)
+	case watch:
+		d.watch(c, msg.watcher, msg.target)
+	case unwatch:
+		d.unwatch(msg.watcher, msg.target)
+	case ActorStoppedEvent:
+		d.terminate(c, msg.PID, TerminatedStopped)
+		// this also drops the watches on remote processes, which are not
+		// terminated when they stop.
+		d.removeWatcher(msg.PID)
+	case ActorMaxRestartsExceededEvent:
+		d.terminate(c, msg.PID, TerminatedMaxRestarts)
+	case RemoteUnreachableEvent:
+		for _, w := range d.watched {
+			if w.pid.Address == msg.ListenAddr {
+				d.terminate(c, w.pid, TerminatedRemoteUnreachable)
+			}
+		}
+	}
+}
+
+func (d *deathWatch) watch(c *Context, watcher, target *PID) {
+	// Remote processes are only terminated when their remote is unreachable,
+	// local processes need to exist.
+	if c.engine.isLocalMessage(target) && c.engine.Registry.get(target) == nil {
+		c.Send(watcher, Terminated{PID: target, Reason: TerminatedNotFound})
+		return
+	}
+	w, ok := d.watched[watchKey(target)]
+	if !ok {
+		w = &watchedProc{pid: target, watchers: NewPIDSet()}
+		d.watched[watchKey(target)] = w
+	}
+	w.watchers.Add(watcher)
+
+	watching, ok := d.watching[watchKey(watcher)]
+	if !ok {
+		watching = NewPIDSet()
+		d.watching[watchKey(watcher)] = watching
+	}
+	watching.Add(target)
+}
+
+func (d *deathWatch) unwatch(watcher, target *PID) {
+	if w, ok := d.watched[watchKey(target)]; ok {
+		w.watchers.Remove(watcher)
+		if w.watchers.Empty() {
+			delete(d.watched, watchKey(target))
+		}
+	}
+	if watching, ok := d.watching[watchKey(watcher)]; ok {
+		watching.Remove(target)
+		if watching.Empty() {
+			delete(d.watching, watchKey(watcher))
+		}
+	}
+}
+
+// terminate notifies all the watchers of the given process.
+func (d *deathWatch) terminate(c *Context, pid *PID, reason TerminatedReason) {
+	w, ok := d.watched[watchKey(pid)]
+	if !ok {
+		return
+	}
+	for _, watcher := range w.watchers.Clone().Values() {
+		c.Send(watcher, Terminated{PID: w.pid, Reason: reason})
+		d.unwatch(watcher, w.pid)
+	}
+}
+
+// removeWatcher drops all the watches of a watcher that stopped.
+func (d *deathWatch) removeWatcher(watcher *PID) {
+	watching, ok := d.watching[watchKey(watcher)]
+	if !ok {
+		return
+	}
+	for _, target := range watching.Clone().Values() {
+		d.unwatch(watcher, target)
+	}
+}
+
+func watchKey(pid *PID) pidKey {
+	return pidKey{address: pid.Address, id: pid.ID}
+}
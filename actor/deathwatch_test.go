@@ -0,0 +1,133 @@
+package actor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// spawnWatcher spawns an actor that watches the given PID and forwards all
+// the Terminated messages it receives on the returned channel.
+func spawnWatcher(e *Engine, target *PID) (*PID, chan Terminated) {
+	terminated := make(chan Terminated, 10)
+	watching := make(chan struct{})
+	pid := e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case Started:
+			c.Watch(target)
+			close(watching)
+		case Terminated:
+			terminated <- msg
+		}
+	}, "watcher")
+	<-watching
+	return pid, terminated
+}
+
+func expectTerminated(t *testing.T, ch chan Terminated, pid *PID, reason TerminatedReason) {
+	select {
+	case msg := <-ch:
+		require.True(t, msg.PID.Equals(pid))
+		require.Equal(t, reason, msg.Reason)
+	case <-time.After(time.Second):
+		t.Fatalf("expected Terminated with reason %s", reason)
+	}
+}
+
+func TestWatchStopped(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	pid := e.SpawnFunc(func(c *Context) {}, "foo")
+	_, terminated := spawnWatcher(e, pid)
+	// make sure the watch is registered before we stop.
+	time.Sleep(time.Millisecond * 10)
+	<-e.Poison(pid).Done()
+	expectTerminated(t, terminated, pid, TerminatedStopped)
+}
+
+func TestWatchNotFound(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	pid := NewPID(LocalLookupAddr, "foo/bar")
+	_, terminated := spawnWatcher(e, pid)
+	expectTerminated(t, terminated, pid, TerminatedNotFound)
+}
+
+func TestWatchMaxRestarts(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			panic("crash")
+		}
+	}, "foo", WithMaxRestarts(0))
+	_, terminated := spawnWatcher(e, pid)
+	time.Sleep(time.Millisecond * 10)
+	e.Send(pid, "crash")
+	expectTerminated(t, terminated, pid, TerminatedMaxRestarts)
+}
+
+func TestWatchRemoteUnreachable(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	pid := NewPID("127.0.0.1:4000", "foo/bar")
+	_, terminated := spawnWatcher(e, pid)
+	time.Sleep(time.Millisecond * 10)
+	e.BroadcastEvent(RemoteUnreachableEvent{ListenAddr: "127.0.0.1:5000"})
+	e.BroadcastEvent(RemoteUnreachableEvent{ListenAddr: "127.0.0.1:4000"})
+	expectTerminated(t, terminated, pid, TerminatedRemoteUnreachable)
+}
+
+func TestWatchRemoteDroppedWithWatcher(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	deadletters := make(chan DeadLetterEvent, 10)
+	sub := e.SpawnFunc(func(c *Context) {
+		if msg, ok := c.Message().(DeadLetterEvent); ok {
+			deadletters <- msg
+		}
+	}, "sub")
+	e.Subscribe(sub)
+	pid := NewPID("127.0.0.1:4000", "foo/bar")
+	watcher, _ := spawnWatcher(e, pid)
+	time.Sleep(time.Millisecond * 10)
+	<-e.Poison(watcher).Done()
+	time.Sleep(time.Millisecond * 10)
+	// the watch of the stopped watcher is gone, hence no Terminated is sent
+	// to it.
+	e.BroadcastEvent(RemoteUnreachableEvent{ListenAddr: "127.0.0.1:4000"})
+	select {
+	case msg := <-deadletters:
+		t.Fatalf("unexpected deadletter %+v", msg)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestUnwatch(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		pid        = e.SpawnFunc(func(c *Context) {}, "foo")
+		terminated = make(chan Terminated, 1)
+		unwatched  = make(chan struct{})
+	)
+	e.SpawnFunc(func(c *Context) {
+		switch c.Message().(type) {
+		case Started:
+			c.Watch(pid)
+			c.Unwatch(pid)
+			close(unwatched)
+		case Terminated:
+			terminated <- c.Message().(Terminated)
+		}
+	}, "watcher")
+	<-unwatched
+	time.Sleep(time.Millisecond * 10)
+	<-e.Poison(pid).Done()
+	select {
+	case <-terminated:
+		t.Fatal("did not expect Terminated after unwatch")
+	case <-time.After(time.Millisecond * 50):
+	}
+}
@@ -0,0 +1,77 @@
+package actor
+
+import "log/slog"
+
+// InboxStore persists the messages of durable inboxes, see WithDurableInbox.
+// The messages are stored under the ID of the PID of their target.
+type InboxStore interface {
+	// Append persists the given envelope and returns its sequence number,
+	// which is greater than the ones of the previous envelopes of the ID.
+	Append(id string, env Envelope) (seq uint64, err error)
+	// Remove removes the envelope with the given sequence number.
+	Remove(id string, seq uint64) error
+	// Pending returns the envelopes of the ID that were not removed, in the
+	// order they were appended.
+	Pending(id string) ([]InboxEntry, error)
+}
+
+// InboxEntry is an envelope persisted by an InboxStore.
+type InboxEntry struct {
+	Seq      uint64
+	Envelope Envelope
+}
+
+// isDurable reports whether the given message is persisted by a durable
+// inbox, the messages that are private to the engine are not.
+func isDurable(msg any) bool {
+	switch msg.(type) {
+	case poisonPill, restartProcess, childFailure, continuation, ReceiveTimeout:
+		return false
+	}
+	return true
+}
+
+// loadPending buffers the pending envelopes of the durable inbox, they are
+// invoked when the process starts, before the messages of the inbox.
+func (p *process) loadPending() {
+	entries, err := p.InboxStore.Pending(p.pid.ID)
+	if err != nil {
+		slog.Error("failed to load the durable inbox", "pid", p.pid, "err", err)
+		return
+	}
+	for _, entry := range entries {
+		env := entry.Envelope
+		env.seq = entry.Seq
+		p.mbuffer = append(p.mbuffer, env)
+	}
+}
+
+// persist appends the envelope to the durable inbox. When it fails the
+// message is still delivered, but it is lost on a crash.
+func (p *process) persist(env *Envelope) {
+	seq, err := p.InboxStore.Append(p.pid.ID, *env)
+	if err != nil {
+		slog.Error("failed to persist message in the durable inbox", "pid", p.pid, "msg", env.Msg, "err", err)
+		return
+	}
+	env.seq = seq
+}
+
+// ack removes the envelope with the given sequence number from the durable
+// inbox, zero is the sequence number of the envelopes that are not in it.
+func (p *process) ack(seq uint64) {
+	if seq == 0 {
+		return
+	}
+	if err := p.InboxStore.Remove(p.pid.ID, seq); err != nil {
+		slog.Error("failed to remove message from the durable inbox", "pid", p.pid, "seq", seq, "err", err)
+	}
+}
+
+// ackCurrent removes the message that was just received from the durable
+// inbox, unless it was stashed, in which case it is removed once the stashed
+// message is received.
+func (p *process) ackCurrent() {
+	p.ack(p.context.seq)
+	p.context.seq = 0
+}
@@ -3,11 +3,15 @@ package actor
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"math"
 	"math/rand"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/anthdm/hollywood/safemap"
 )
 
 // Remoter is an interface that abstract a remote that is tied to an engine.
@@ -32,11 +36,33 @@ type Engine struct {
 	address     string
 	remote      Remoter
 	eventStream *PID
+	deadLetters *PID
+	timers      *timerWheel
+
+	// deathWatch is spawned the first time a process is watched.
+	deathWatch     *PID
+	deathWatchOnce sync.Once
+
+	// system holds the IDs of the processes of the engine and the remote,
+	// those are not stopped on Shutdown.
+	system   *safemap.SafeMap[string, struct{}]
+	stopping atomic.Bool
+	// responseID is used to generate the IDs of the responses.
+	responseID atomic.Uint64
+	// middleware is applied to all the actors spawned by the engine.
+	middleware []MiddlewareFunc
+	clock      Clock
+	// scheduler, if set, runs all the actors without a scheduler of their own.
+	scheduler Scheduler
 }
 
 // EngineConfig holds the configuration of the engine.
 type EngineConfig struct {
-	remote Remoter
+	remote      Remoter
+	deadLetters *DeadLetterStoreConfig
+	middleware  []MiddlewareFunc
+	clock       Clock
+	scheduler   Scheduler
 }
 
 // NewEngineConfig returns a new default EngineConfig.
@@ -51,9 +77,54 @@ func (config EngineConfig) WithRemote(remote Remoter) EngineConfig {
 	return config
 }
 
+// WithDeadLetterStore enables the deadletter store of the engine, which keeps
+// a history of the deadletters that can be queried and replayed. See
+// Engine.DeadLetters.
+func (config EngineConfig) WithDeadLetterStore(storeConfig DeadLetterStoreConfig) EngineConfig {
+	config.deadLetters = &storeConfig
+	return config
+}
+
+// WithMiddleware sets middleware that is applied to all the actors spawned by
+// the engine, before the middleware of the actor itself. The processes of the
+// engine, such as the event stream, run without it.
+func (config EngineConfig) WithMiddleware(mw ...MiddlewareFunc) EngineConfig {
+	config.middleware = append(config.middleware[:len(config.middleware):len(config.middleware)], mw...)
+	return config
+}
+
+// WithClock sets the clock of the engine, which drives its timers, timeouts,
+// restart delays, the block timeout of bounded inboxes and the idle timeout
+// of remote connections. Use a VirtualClock to control time in tests. Only
+// the dial retries of the remote, which wait on the network, stay on the
+// system clock.
+func (config EngineConfig) WithClock(clock Clock) EngineConfig {
+	config.clock = clock
+	return config
+}
+
+// WithScheduler sets the scheduler of all the actors of the engine, including
+// the processes of the engine itself, except for the actors spawned with a
+// scheduler of their own. Together with a VirtualClock, a
+// DeterministicScheduler makes the engine run single-threaded and
+// reproducibly.
+func (config EngineConfig) WithScheduler(scheduler Scheduler) EngineConfig {
+	config.scheduler = scheduler
+	return config
+}
+
 // NewEngine returns a new actor Engine given an EngineConfig.
 func NewEngine(config EngineConfig) (*Engine, error) {
-	e := &Engine{}
+	clock := config.clock
+	if clock == nil {
+		clock = SystemClock()
+	}
+	e := &Engine{
+		system:    safemap.New[string, struct{}](),
+		timers:    newTimerWheel(clock),
+		clock:     clock,
+		scheduler: config.scheduler,
+	}
 	e.Registry = newRegistry(e) // need to init the registry in case we want a custom deadletter
 	e.address = LocalLookupAddr
 	if config.remote != nil {
@@ -65,12 +136,29 @@ func NewEngine(config EngineConfig) (*Engine, error) {
 		}
 	}
 	e.eventStream = e.Spawn(newEventStream(), "eventstream")
+	if config.deadLetters != nil {
+		e.deadLetters = e.Spawn(newDeadLetterStore(*config.deadLetters), "deadletters")
+	}
+	e.Registry.each(func(id string, _ Processer) {
+		e.system.Set(id, struct{}{})
+	})
+	// the processes of the engine and the remote run without the middleware.
+	e.middleware = config.middleware
 	return e, nil
 }
 
 // Spawn spawns a process that will producer by the given Producer and
-// can be configured with the given opts.
+// can be configured with the given opts. Spawn returns nil when the engine
+// is shutting down.
 func (e *Engine) Spawn(p Producer, kind string, opts ...OptFunc) *PID {
+	if e.isStopping() {
+		slog.Warn("engine is shutting down", "func", "Spawn", "kind", kind)
+		return nil
+	}
+	return e.spawn(p, kind, opts...)
+}
+
+func (e *Engine) spawn(p Producer, kind string, opts ...OptFunc) *PID {
 	options := DefaultOpts(p)
 	options.Kind = kind
 	for _, opt := range opts {
@@ -85,6 +173,14 @@ func (e *Engine) Spawn(p Producer, kind string, opts ...OptFunc) *PID {
 	return e.SpawnProc(proc)
 }
 
+// spawnSystem spawns a process of the engine, which is not stopped on
+// Shutdown and runs without the middleware of the engine.
+func (e *Engine) spawnSystem(p Producer, kind string) *PID {
+	id := strconv.Itoa(rand.Intn(math.MaxInt))
+	e.system.Set(kind+pidSeparator+id, struct{}{})
+	return e.spawn(p, kind, WithID(id))
+}
+
 // SpawnFunc spawns the given function as a stateless receiver/actor.
 func (e *Engine) SpawnFunc(f func(*Context), kind string, opts ...OptFunc) *PID {
 	return e.Spawn(newFuncReceiver(f), kind, opts...)
@@ -104,14 +200,38 @@ func (e *Engine) Address() string {
 	return e.address
 }
 
+// Clock returns the clock of the engine.
+func (e *Engine) Clock() Clock {
+	return e.clock
+}
+
+// InboxLen returns the number of messages waiting in the inbox of the local
+// process with the given PID. It returns false if no such process exists.
+func (e *Engine) InboxLen(pid *PID) (int, bool) {
+	proc, ok := e.Registry.get(pid).(*process)
+	if !ok {
+		return 0, false
+	}
+	inbox, ok := proc.inbox.(*Inbox)
+	if !ok {
+		return 0, false
+	}
+	return int(inbox.Len()), true
+}
+
 // Request sends the given message to the given PID as a "Request", returning
 // a response that will resolve in the future. Calling Response.Result() will
 // block until the deadline is exceeded or the response is being resolved.
 func (e *Engine) Request(pid *PID, msg any, timeout time.Duration) *Response {
+	return e.request(pid, Envelope{Msg: msg}, timeout, e.send)
+}
+
+func (e *Engine) request(pid *PID, env Envelope, timeout time.Duration, send SendFunc) *Response {
 	resp := NewResponse(e, timeout)
 	e.Registry.add(resp)
 
-	e.SendWithSender(pid, msg, resp.PID())
+	env.Sender = resp.PID()
+	send(pid, env)
 
 	return resp
 }
@@ -120,25 +240,31 @@ func (e *Engine) Request(pid *PID, msg any, timeout time.Duration) *Response {
 // given sender. Receivers receiving this message can check the sender
 // by calling Context.Sender().
 func (e *Engine) SendWithSender(pid *PID, msg any, sender *PID) {
-	e.send(pid, msg, sender)
+	e.send(pid, Envelope{Msg: msg, Sender: sender})
+}
+
+// SendEnvelope sends the message of the given Envelope to the given PID,
+// keeping its sender and its SpanContext.
+func (e *Engine) SendEnvelope(pid *PID, env Envelope) {
+	e.send(pid, env)
 }
 
 // Send sends the given message to the given PID. If the message cannot be
 // delivered due to the fact that the given process is not registered.
 // The message will be sent to the DeadLetter process instead.
 func (e *Engine) Send(pid *PID, msg any) {
-	e.send(pid, msg, nil)
+	e.send(pid, Envelope{Msg: msg})
 }
 
 // BroadcastEvent will broadcast the given message over the eventstream, notifying all
 // actors that are subscribed.
 func (e *Engine) BroadcastEvent(msg any) {
 	if e.eventStream != nil {
-		e.send(e.eventStream, msg, nil)
+		e.send(e.eventStream, Envelope{Msg: msg})
 	}
 }
 
-func (e *Engine) send(pid *PID, msg any, sender *PID) {
+func (e *Engine) send(pid *PID, env Envelope) {
 	// TODO: We might want to log something here. Not yet decided
 	// what could make sense. Send to dead letter or as event?
 	// Dead letter would make sense cause the destination is not
@@ -147,14 +273,18 @@ func (e *Engine) send(pid *PID, msg any, sender *PID) {
 		return
 	}
 	if e.isLocalMessage(pid) {
-		e.SendLocal(pid, msg, sender)
+		e.sendLocal(pid, env)
 		return
 	}
 	if e.remote == nil {
-		e.BroadcastEvent(EngineRemoteMissingEvent{Target: pid, Sender: sender, Message: msg})
+		e.BroadcastEvent(EngineRemoteMissingEvent{Target: pid, Sender: env.Sender, Message: env.Msg})
+		return
+	}
+	if remote, ok := e.remote.(EnvelopeSender); ok {
+		remote.SendEnvelope(pid, env)
 		return
 	}
-	e.remote.Send(pid, msg, sender)
+	e.remote.Send(pid, env.Msg, env.Sender)
 }
 
 // SendRepeater is a struct that can be used to send a repeating message to a given PID.
@@ -166,31 +296,24 @@ type SendRepeater struct {
 	target   *PID
 	msg      any
 	interval time.Duration
-	cancelch chan struct{}
-}
-
-func (sr SendRepeater) start() {
-	ticker := time.NewTicker(sr.interval)
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				sr.engine.SendWithSender(sr.target, sr.msg, sr.self)
-			case <-sr.cancelch:
-				ticker.Stop()
-				return
-			}
-		}
-	}()
+	timer    *Timer
+}
+
+func (sr *SendRepeater) start(onStop func(*Timer)) *Timer {
+	sr.timer = sr.engine.timers.newTimer(func() {
+		sr.engine.send(sr.target, Envelope{Msg: sr.msg, Sender: sr.self, noWait: true})
+	}, sr.interval, onStop)
+	return sr.engine.timers.start(sr.timer, sr.interval)
 }
 
 // Stop will stop the repeating message.
 func (sr SendRepeater) Stop() {
-	close(sr.cancelch)
+	sr.timer.Stop()
 }
 
 // SendRepeat will send the given message to the given PID each given interval.
 // It will return a SendRepeater struct that can stop the repeating message by calling Stop().
+// The interval has the same resolution and bounds as the duration of SendAfter.
 func (e *Engine) SendRepeat(pid *PID, msg any, interval time.Duration) SendRepeater {
 	clonedPID := *pid.CloneVT()
 	sr := SendRepeater{
@@ -199,12 +322,23 @@ func (e *Engine) SendRepeat(pid *PID, msg any, interval time.Duration) SendRepea
 		target:   &clonedPID,
 		interval: interval,
 		msg:      msg,
-		cancelch: make(chan struct{}, 1),
 	}
-	sr.start()
+	sr.start(nil)
 	return sr
 }
 
+// SendAfter will send the given message to the given PID after the given duration.
+// The returned Timer can be used to cancel the message before it is sent.
+// The duration is rounded up to the millisecond, durations longer than the
+// span of the timer wheel are fine. A full inbox with the OverflowBlock
+// policy doesn't hold up the other timers, the message waits on its own.
+func (e *Engine) SendAfter(pid *PID, msg any, d time.Duration) *Timer {
+	t := e.timers.newTimer(func() {
+		e.send(pid, Envelope{Msg: msg, noWait: true})
+	}, 0, nil)
+	return e.timers.start(t, d)
+}
+
 // Stop will send a non-graceful poisonPill message to the process that is associated with the given PID.
 // The process will shut down immediately. A context is being returned that can be used to block / wait
 // until the process is stopped.
@@ -251,22 +385,67 @@ func (e *Engine) sendPoisonPill(ctx context.Context, graceful bool, pid *PID) co
 // registry, the message will be sent to the DeadLetter process instead. If there is no deadletter
 // process registered, the function will panic.
 func (e *Engine) SendLocal(pid *PID, msg any, sender *PID) {
+	e.sendLocal(pid, Envelope{Msg: msg, Sender: sender})
+}
+
+// SendLocalEnvelope behaves like SendLocal, keeping the sender and the
+// SpanContext of the given Envelope.
+func (e *Engine) SendLocalEnvelope(pid *PID, env Envelope) {
+	e.sendLocal(pid, env)
+}
+
+func (e *Engine) sendLocal(pid *PID, env Envelope) {
 	proc := e.Registry.get(pid)
 	if proc == nil {
+		// A deadletter that can't be delivered to a subscriber of the event
+		// stream is dropped, otherwise it would loop through the event stream
+		// forever and starve the scheduler.
+		if _, ok := env.Msg.(DeadLetterEvent); ok {
+			return
+		}
 		// broadcast a deadLetter message
 		e.BroadcastEvent(DeadLetterEvent{
 			Target:  pid,
-			Message: msg,
-			Sender:  sender,
+			Message: env.Msg,
+			Sender:  env.Sender,
+			Headers: env.Headers,
+			Trace:   env.Trace,
 		})
 		return
 	}
-	proc.Send(pid, msg, sender)
+	if p, ok := proc.(*process); ok {
+		p.sendEnvelope(env)
+		return
+	}
+	proc.Send(pid, env.Msg, env.Sender)
 }
 
-// Subscribe will subscribe the given PID to the event stream.
-func (e *Engine) Subscribe(pid *PID) {
-	e.Send(e.eventStream, eventSub{pid: pid})
+// Subscribe will subscribe the given PID to the event stream. When filters
+// are given, only the events matching any of them are sent to the PID. Calling
+// Subscribe again for the same PID replaces its filters.
+func (e *Engine) Subscribe(pid *PID, filters ...EventFilter) {
+	e.Send(e.eventStream, eventSub{pid: pid, filters: filters})
+}
+
+// SubscribeFunc calls the given function with each event of type T that is
+// broadcasted over the event stream, one at a time. It returns a function
+// that unsubscribes it.
+func SubscribeFunc[T any](e *Engine, fn func(T)) (unsubscribe func()) {
+	// subscribers keep receiving the events while the engine shuts down.
+	pid := e.spawnSystem(newFuncReceiver(func(c *Context) {
+		if event, ok := c.Message().(T); ok {
+			fn(event)
+		}
+	}), "subscriber")
+	e.Subscribe(pid, EventType[T]())
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			e.Unsubscribe(pid)
+			e.Poison(pid)
+			e.system.Delete(pid.ID)
+		})
+	}
 }
 
 // Unsubscribe will un subscribe the given PID from the event stream.
@@ -274,6 +453,14 @@ func (e *Engine) Unsubscribe(pid *PID) {
 	e.Send(e.eventStream, eventUnsub{pid: pid})
 }
 
+// watchers returns the PID of the deathwatch actor, spawning it if needed.
+func (e *Engine) watchers() *PID {
+	e.deathWatchOnce.Do(func() {
+		e.deathWatch = e.spawnSystem(newDeathWatch(), "deathwatch")
+	})
+	return e.deathWatch
+}
+
 func (e *Engine) isLocalMessage(pid *PID) bool {
 	if pid == nil {
 		return false
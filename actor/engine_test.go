@@ -480,3 +480,35 @@ func TestMultipleStops(t *testing.T) {
 		<-done
 	}
 }
+
+func TestEngineMiddleware(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	record := func(name string) MiddlewareFunc {
+		return func(next ReceiveFunc) ReceiveFunc {
+			return func(c *Context) {
+				if _, ok := c.Message().(string); ok {
+					mu.Lock()
+					order = append(order, name)
+					mu.Unlock()
+				}
+				next(c)
+			}
+		}
+	}
+	e, err := NewEngine(NewEngineConfig().WithMiddleware(record("engine")))
+	require.NoError(t, err)
+	done := make(chan struct{})
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			close(done)
+		}
+	}, "test", WithMiddleware(record("actor")))
+	e.Send(pid, "foo")
+	<-done
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"engine", "actor"}, order)
+}
@@ -55,12 +55,28 @@ type ActorRestartedEvent struct {
 	Stacktrace []byte
 	Reason     any
 	Restarts   int32
+	// Delay is the time the actor waits before it gets started again.
+	Delay time.Duration
 }
 
 func (e ActorRestartedEvent) Log() (slog.Level, string, []any) {
 	return slog.LevelError, "Actor crashed and restarted",
 		[]any{"pid", e.PID.GetID(), "stack", string(e.Stacktrace),
-			"reason", e.Reason, "restarts", e.Restarts}
+			"reason", e.Reason, "restarts", e.Restarts, "delay", e.Delay}
+}
+
+// ActorResumedEvent is broadcasted when an actor crashes and its supervisor
+// decided to resume it. The message that caused the crash is dropped.
+type ActorResumedEvent struct {
+	PID        *PID
+	Timestamp  time.Time
+	Stacktrace []byte
+	Reason     any
+}
+
+func (e ActorResumedEvent) Log() (slog.Level, string, []any) {
+	return slog.LevelWarn, "Actor crashed and resumed",
+		[]any{"pid", e.PID.GetID(), "stack", string(e.Stacktrace), "reason", e.Reason}
 }
 
 // ActorMaxRestartsExceededEvent gets created if an actor crashes too many times
@@ -102,9 +118,27 @@ type RemoteUnreachableEvent struct {
 	ListenAddr string
 }
 
+// InboxOverflowEvent gets published for each message that is dropped
+// because the inbox of the target actor is full.
+type InboxOverflowEvent struct {
+	PID     *PID
+	Message any
+	Sender  *PID
+	Policy  OverflowPolicy
+}
+
+func (e InboxOverflowEvent) Log() (slog.Level, string, []any) {
+	return slog.LevelWarn, "Inbox overflow, message dropped",
+		[]any{"pid", e.PID.GetID(), "policy", e.Policy.String()}
+}
+
 // DeadLetterEvent is delivered to the deadletter actor when a message can't be delivered to it's recipient
 type DeadLetterEvent struct {
 	Target  *PID
 	Message any
 	Sender  *PID
+	// Headers and Trace are the ones of the envelope that couldn't be
+	// delivered.
+	Headers map[string]string
+	Trace   SpanContext
 }
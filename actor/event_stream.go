@@ -7,7 +7,8 @@ import (
 
 // eventSub is the message that will be send to subscribe to the event stream.
 type eventSub struct {
-	pid *PID
+	pid     *PID
+	filters []EventFilter
 }
 
 // EventUnSub is the message that will be send to unsubscribe from the event stream.
@@ -15,14 +16,42 @@ type eventUnsub struct {
 	pid *PID
 }
 
+// EventFilter decides if an event is forwarded to a subscriber of the event
+// stream. Filters run on the event stream, hence they must be fast and must
+// not block.
+type EventFilter func(event any) bool
+
+// TopicEvent is implemented by events that are published on a topic.
+type TopicEvent interface {
+	Topic() string
+}
+
+// EventType returns an EventFilter that matches the events of type T. T can
+// also be an interface.
+func EventType[T any]() EventFilter {
+	return func(event any) bool {
+		_, ok := event.(T)
+		return ok
+	}
+}
+
+// EventTopic returns an EventFilter that matches the events implementing
+// TopicEvent with the given topic.
+func EventTopic(topic string) EventFilter {
+	return func(event any) bool {
+		e, ok := event.(TopicEvent)
+		return ok && e.Topic() == topic
+	}
+}
+
 type eventStream struct {
-	subs map[*PID]bool
+	subs map[pidKey]eventSub
 }
 
 func newEventStream() Producer {
 	return func() Receiver {
 		return &eventStream{
-			subs: make(map[*PID]bool),
+			subs: make(map[pidKey]eventSub),
 		}
 	}
 }
@@ -33,9 +62,9 @@ func newEventStream() Producer {
 func (e *eventStream) Receive(c *Context) {
 	switch msg := c.Message().(type) {
 	case eventSub:
-		e.subs[msg.pid] = true
+		e.subs[watchKey(msg.pid)] = msg
 	case eventUnsub:
-		delete(e.subs, msg.pid)
+		delete(e.subs, watchKey(msg.pid))
 	default:
 		// check if we should log the event, if so, log it with the relevant level, message and attributes
 		logMsg, ok := c.Message().(EventLogger)
@@ -43,8 +72,24 @@ func (e *eventStream) Receive(c *Context) {
 			level, msg, attr := logMsg.Log()
 			slog.Log(context.Background(), level, msg, attr...)
 		}
-		for sub := range e.subs {
-			c.Forward(sub)
+		for _, sub := range e.subs {
+			if match(sub.filters, c.Message()) {
+				c.Forward(sub.pid)
+			}
+		}
+	}
+}
+
+// match returns true if the event matches any of the filters, or if there
+// are no filters.
+func match(filters []EventFilter, event any) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, filter := range filters {
+		if filter(event) {
+			return true
 		}
 	}
+	return false
 }
@@ -4,8 +4,10 @@ import (
 	fmt "fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type CustomEvent struct {
@@ -79,3 +81,126 @@ func TestEventStreamActorStoppedEvent(t *testing.T) {
 
 	wg.Wait()
 }
+
+type topicEvent struct {
+	topic string
+}
+
+func (e topicEvent) Topic() string { return e.topic }
+
+func TestEventStreamFilters(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	received := make(chan any, 10)
+	subscribe := func(filters ...EventFilter) {
+		subscribed := make(chan struct{})
+		e.SpawnFunc(func(c *Context) {
+			switch msg := c.Message().(type) {
+			case Started:
+				c.Engine().Subscribe(c.PID(), filters...)
+				close(subscribed)
+			case CustomEvent, topicEvent:
+				received <- msg
+			}
+		}, "sub")
+		<-subscribed
+	}
+	subscribe(EventType[CustomEvent]())
+	subscribe(EventTopic("orders"))
+	subscribe(func(event any) bool {
+		e, ok := event.(CustomEvent)
+		return ok && e.msg == "custom"
+	})
+
+	e.BroadcastEvent(topicEvent{topic: "payments"})
+	e.BroadcastEvent(topicEvent{topic: "orders"})
+	e.BroadcastEvent(CustomEvent{msg: "custom"})
+
+	var events []any
+	for i := 0; i < 3; i++ {
+		select {
+		case event := <-received:
+			events = append(events, event)
+		case <-time.After(time.Second):
+			t.Fatal("expected 3 events")
+		}
+	}
+	assert.ElementsMatch(t, []any{
+		topicEvent{topic: "orders"},
+		CustomEvent{msg: "custom"},
+		CustomEvent{msg: "custom"},
+	}, events)
+	select {
+	case event := <-received:
+		t.Fatalf("unexpected event %v", event)
+	case <-time.After(time.Millisecond * 20):
+	}
+}
+
+func TestEventStreamSubscribeEqualPID(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	received := make(chan any, 10)
+	pid := e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case CustomEvent, topicEvent:
+			received <- msg
+		}
+	}, "sub")
+	// equal PIDs from other values refer to the same subscription.
+	e.Subscribe(NewPID(pid.Address, pid.ID), EventType[CustomEvent]())
+	e.Subscribe(NewPID(pid.Address, pid.ID), EventTopic("orders"))
+	e.BroadcastEvent(CustomEvent{msg: "custom"})
+	e.BroadcastEvent(topicEvent{topic: "orders"})
+	assert.Equal(t, topicEvent{topic: "orders"}, <-received)
+
+	e.Unsubscribe(NewPID(pid.Address, pid.ID))
+	e.BroadcastEvent(topicEvent{topic: "orders"})
+	select {
+	case event := <-received:
+		t.Fatalf("unexpected event %v", event)
+	case <-time.After(time.Millisecond * 20):
+	}
+}
+
+func TestSubscribeFunc(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	received := make(chan CustomEvent, 10)
+	unsubscribe := SubscribeFunc(e, func(event CustomEvent) {
+		received <- event
+	})
+	e.BroadcastEvent(topicEvent{topic: "orders"})
+	e.BroadcastEvent(CustomEvent{msg: "foo"})
+	assert.Equal(t, CustomEvent{msg: "foo"}, <-received)
+
+	unsubscribe()
+	e.BroadcastEvent(CustomEvent{msg: "bar"})
+	select {
+	case event := <-received:
+		t.Fatalf("unexpected event after unsubscribe %v", event)
+	case <-time.After(time.Millisecond * 20):
+	}
+}
+
+func TestEventStreamDeadSubscriberDoesNotLoop(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	deadletters := make(chan DeadLetterEvent, 100)
+	SubscribeFunc(e, func(event DeadLetterEvent) {
+		deadletters <- event
+	})
+	// a subscriber that is gone without unsubscribing.
+	e.Subscribe(NewPID(e.Address(), "ghost/1"))
+	e.BroadcastEvent(CustomEvent{msg: "foo"})
+
+	event := <-deadletters
+	assert.Equal(t, CustomEvent{msg: "foo"}, event.Message)
+	// the deadletter of the event is not delivered to the ghost either, but
+	// that one is dropped instead of becoming another deadletter.
+	select {
+	case event := <-deadletters:
+		t.Fatalf("unexpected deadletter of %T", event.Message)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
@@ -2,7 +2,9 @@ package actor
 
 import (
 	"runtime"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/anthdm/hollywood/ringbuffer"
 )
@@ -10,6 +12,7 @@ import (
 const (
 	defaultThroughput = 300
 	messageBatchSize  = 1024 * 4
+	priorityInboxSize = 8
 )
 
 const (
@@ -19,23 +22,40 @@ const (
 	running
 )
 
-type Scheduler interface {
-	Schedule(fn func())
-	Throughput() int
-}
-
-type goscheduler int
-
-func (goscheduler) Schedule(fn func()) {
-	go fn()
-}
+// OverflowPolicy decides what happens with messages that are sent to an
+// inbox that is full. The size of the inbox is set with WithInboxSize.
+type OverflowPolicy int
 
-func (sched goscheduler) Throughput() int {
-	return int(sched)
-}
+const (
+	// OverflowGrow grows the inbox, so it never overflows. This is the default.
+	OverflowGrow OverflowPolicy = iota
+	// OverflowDropNewest drops the message that is being sent.
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest message in the inbox to make room.
+	OverflowDropOldest
+	// OverflowBlock blocks the sender until there is room in the inbox or
+	// the block timeout is exceeded, in which case the message is dropped.
+	OverflowBlock
+	// OverflowDeadLetter drops the message that is being sent and routes it
+	// to the deadletters.
+	OverflowDeadLetter
+)
 
-func NewScheduler(throughput int) Scheduler {
-	return goscheduler(throughput)
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowGrow:
+		return "grow"
+	case OverflowDropNewest:
+		return "drop_newest"
+	case OverflowDropOldest:
+		return "drop_oldest"
+	case OverflowBlock:
+		return "block"
+	case OverflowDeadLetter:
+		return "deadletter"
+	default:
+		return "unknown"
+	}
 }
 
 type Inboxer interface {
@@ -44,26 +64,124 @@ type Inboxer interface {
 	Stop() error
 }
 
+// PriorityMessage is a marker interface for messages that skip the queue.
+// They are delivered before any regular message waiting in the inbox and
+// are never dropped by the overflow policy of the inbox.
+type PriorityMessage interface {
+	PriorityMessage()
+}
+
 type Inbox struct {
 	rb         *ringbuffer.RingBuffer[Envelope]
+	prb        *ringbuffer.RingBuffer[Envelope] // system and priority messages, always drained first.
 	proc       Processer
 	scheduler  Scheduler
+	clock      Clock
+	throughput int
 	procStatus int32
+
+	size         int64
+	overflow     OverflowPolicy
+	blockTimeout time.Duration
+	onOverflow   func(Envelope)
+	// spaceMu guards spacech, which is closed and replaced each time
+	// messages are popped, waking up all blocked senders.
+	spaceMu sync.Mutex
+	spacech chan struct{}
 }
 
 func NewInbox(size int) *Inbox {
 	return &Inbox{
 		rb:         ringbuffer.New[Envelope](int64(size)),
+		prb:        ringbuffer.New[Envelope](priorityInboxSize),
 		scheduler:  NewScheduler(defaultThroughput),
+		clock:      SystemClock(),
 		procStatus: stopped,
+		size:       int64(size),
 	}
 }
 
+// setOverflow bounds the inbox to its size. Messages that do not fit are
+// handled by the given policy and passed to onOverflow when dropped.
+func (in *Inbox) setOverflow(policy OverflowPolicy, blockTimeout time.Duration, onOverflow func(Envelope)) {
+	in.overflow = policy
+	in.blockTimeout = blockTimeout
+	in.onOverflow = onOverflow
+	in.spacech = make(chan struct{})
+}
+
 func (in *Inbox) Send(msg Envelope) {
-	in.rb.Push(msg)
+	noWait := msg.noWait
+	msg.noWait = false
+	switch {
+	case isPriorityMessage(msg.Msg):
+		in.prb.Push(msg)
+	// a graceful poison pill is never dropped, otherwise a full inbox can't be stopped.
+	case in.overflow == OverflowGrow || isEngineMessage(msg.Msg):
+		in.rb.Push(msg)
+	case noWait && in.overflow == OverflowBlock:
+		if !in.rb.TryPush(msg, in.size) {
+			go func() {
+				in.pushBounded(msg)
+				in.schedule()
+			}()
+			return
+		}
+	default:
+		in.pushBounded(msg)
+	}
 	in.schedule()
 }
 
+func (in *Inbox) pushBounded(msg Envelope) {
+	switch in.overflow {
+	case OverflowDropOldest:
+		if dropped, ok := in.rb.PushEvict(msg, in.size); ok {
+			in.dropped(dropped)
+		}
+	case OverflowBlock:
+		expired := make(chan struct{})
+		timer := in.clock.AfterFunc(in.blockTimeout, func() { close(expired) })
+		defer timer.Stop()
+		for {
+			// grab the channel before pushing, so we can't miss a pop.
+			spacech := in.waitSpace()
+			if in.rb.TryPush(msg, in.size) {
+				return
+			}
+			select {
+			case <-spacech:
+			case <-expired:
+				in.dropped(msg)
+				return
+			}
+		}
+	default:
+		if !in.rb.TryPush(msg, in.size) {
+			in.dropped(msg)
+		}
+	}
+}
+
+func (in *Inbox) dropped(msg Envelope) {
+	if in.onOverflow != nil {
+		in.onOverflow(msg)
+	}
+}
+
+func (in *Inbox) waitSpace() <-chan struct{} {
+	in.spaceMu.Lock()
+	defer in.spaceMu.Unlock()
+	return in.spacech
+}
+
+func (in *Inbox) notifySpace() {
+	in.spaceMu.Lock()
+	defer in.spaceMu.Unlock()
+	close(in.spacech)
+	in.spacech = make(chan struct{})
+}
+
 func (in *Inbox) schedule() {
 	if atomic.CompareAndSwapInt32(&in.procStatus, idle, running) {
 		in.scheduler.Schedule(in.process)
@@ -72,7 +190,7 @@ func (in *Inbox) schedule() {
 
 func (in *Inbox) process() {
 	in.run()
-	if atomic.CompareAndSwapInt32(&in.procStatus, running, idle) && in.rb.Len() > 0 {
+	if atomic.CompareAndSwapInt32(&in.procStatus, running, idle) && in.Len() > 0 {
 		// messages might have been added to the ring-buffer between the last pop and the transition to idle.
 		// if this is the case, then we should schedule again
 		in.schedule()
@@ -80,15 +198,31 @@ func (in *Inbox) process() {
 }
 
 func (in *Inbox) run() {
-	i, t := 0, in.scheduler.Throughput()
+	i, t := 0, in.throughput
+	if t <= 0 {
+		t = in.scheduler.Throughput()
+	}
 	for atomic.LoadInt32(&in.procStatus) != stopped {
 		if i > t {
+			// the default scheduler runs each inbox on its own goroutine,
+			// the other schedulers run the next inbox once we return and
+			// we are scheduled again by process.
+			if _, ok := in.scheduler.(goscheduler); !ok {
+				return
+			}
 			i = 0
 			runtime.Gosched()
 		}
 		i++
 
+		if msgs, ok := in.prb.PopN(messageBatchSize); ok && len(msgs) > 0 {
+			in.proc.Invoke(msgs)
+			continue
+		}
 		if msgs, ok := in.rb.PopN(messageBatchSize); ok && len(msgs) > 0 {
+			if in.overflow == OverflowBlock {
+				in.notifySpace()
+			}
 			in.proc.Invoke(msgs)
 		} else {
 			return
@@ -96,6 +230,21 @@ func (in *Inbox) run() {
 	}
 }
 
+// takePriority pops the priority messages that were sent while a batch of
+// regular messages is processed, so they don't wait for the whole batch.
+func (in *Inbox) takePriority() []Envelope {
+	if in.prb.Len() == 0 {
+		return nil
+	}
+	msgs, _ := in.prb.PopN(messageBatchSize)
+	return msgs
+}
+
+// Len returns the number of messages waiting in the inbox.
+func (in *Inbox) Len() int64 {
+	return in.rb.Len() + in.prb.Len()
+}
+
 func (in *Inbox) Start(proc Processer) {
 	// transition to "starting" and then "idle" to ensure no race condition on in.proc
 	if atomic.CompareAndSwapInt32(&in.procStatus, stopped, starting) {
@@ -105,7 +254,40 @@ func (in *Inbox) Start(proc Processer) {
 	}
 }
 
+// pause stops processing messages until the inbox is started again. The
+// messages sent in the meantime are kept.
+func (in *Inbox) pause() {
+	atomic.StoreInt32(&in.procStatus, stopped)
+}
+
 func (in *Inbox) Stop() error {
 	atomic.StoreInt32(&in.procStatus, stopped)
+	// a pinned scheduler is dedicated to this inbox.
+	if s, ok := in.scheduler.(*WorkerPoolScheduler); ok && s.pinned {
+		s.Stop()
+	}
+	if in.overflow == OverflowBlock {
+		in.notifySpace()
+	}
 	return nil
 }
+
+// isPriorityMessage reports whether msg is delivered through the priority
+// lane. A graceful poison pill is not, it must wait for the queued messages.
+func isPriorityMessage(msg any) bool {
+	switch m := msg.(type) {
+	case poisonPill:
+		return !m.graceful
+	case restartProcess, childFailure, PriorityMessage:
+		return true
+	}
+	return false
+}
+
+func isEngineMessage(msg any) bool {
+	switch msg.(type) {
+	case poisonPill, restartProcess, childFailure:
+		return true
+	}
+	return false
+}
@@ -85,3 +85,191 @@ func TestInboxStop(t *testing.T) {
 	<-done
 	require.True(t, atomic.LoadInt32(&inbox.procStatus) == stopped)
 }
+
+func TestInboxOverflow(t *testing.T) {
+	tests := []struct {
+		policy   OverflowPolicy
+		expected []int
+		dropped  []int
+	}{
+		{policy: OverflowDropNewest, expected: []int{0, 1, 2}, dropped: []int{3, 4}},
+		{policy: OverflowDeadLetter, expected: []int{0, 1, 2}, dropped: []int{3, 4}},
+		{policy: OverflowDropOldest, expected: []int{2, 3, 4}, dropped: []int{0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			var dropped []int
+			inbox := NewInbox(3)
+			inbox.setOverflow(tt.policy, 0, func(e Envelope) {
+				dropped = append(dropped, e.Msg.(int))
+			})
+			// the inbox is not started, hence nothing is processed.
+			for i := 0; i < 5; i++ {
+				inbox.Send(Envelope{Msg: i})
+			}
+			require.Equal(t, tt.dropped, dropped)
+			msgs, _ := inbox.rb.PopN(10)
+			received := make([]int, len(msgs))
+			for i, msg := range msgs {
+				received[i] = msg.Msg.(int)
+			}
+			require.Equal(t, tt.expected, received)
+		})
+	}
+}
+
+func TestInboxOverflowBlock(t *testing.T) {
+	var (
+		inbox     = NewInbox(1)
+		release   = make(chan struct{})
+		processed = make(chan Envelope, 10)
+		dropped   = make(chan Envelope, 10)
+	)
+	inbox.setOverflow(OverflowBlock, time.Millisecond*20, func(e Envelope) {
+		dropped <- e
+	})
+	inbox.Send(Envelope{Msg: 1})
+	// nobody is processing, the second message will time out.
+	inbox.Send(Envelope{Msg: 2})
+	require.Equal(t, 2, (<-dropped).Msg)
+
+	sent := make(chan struct{})
+	inbox.setOverflow(OverflowBlock, time.Second, nil)
+	go func() {
+		inbox.Send(Envelope{Msg: 3})
+		close(sent)
+	}()
+	select {
+	case <-sent:
+		t.Fatal("expected sender to block")
+	case <-time.After(time.Millisecond * 10):
+	}
+	inbox.Start(MockProcesser{
+		processFunc: func(envelopes []Envelope) {
+			<-release
+			for _, e := range envelopes {
+				processed <- e
+			}
+		},
+	})
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("expected sender to be unblocked")
+	}
+	close(release)
+	require.Equal(t, 1, (<-processed).Msg)
+	require.Equal(t, 3, (<-processed).Msg)
+	inbox.Stop()
+}
+
+func TestInboxOverflowBlockClock(t *testing.T) {
+	var (
+		clock   = NewVirtualClock(time.Unix(0, 0))
+		inbox   = NewInbox(1)
+		dropped = make(chan Envelope, 10)
+	)
+	inbox.clock = clock
+	inbox.setOverflow(OverflowBlock, time.Second, func(e Envelope) {
+		dropped <- e
+	})
+	inbox.Send(Envelope{Msg: 1})
+	go inbox.Send(Envelope{Msg: 2})
+	require.Eventually(t, func() bool {
+		return clock.Pending() == 1
+	}, time.Second, time.Millisecond)
+	select {
+	case <-dropped:
+		t.Fatal("expected the sender to block until the clock advanced")
+	case <-time.After(time.Millisecond * 10):
+	}
+	clock.Advance(time.Second)
+	require.Equal(t, 2, (<-dropped).Msg)
+}
+
+func TestInboxOverflowEvent(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		overflows = make(chan InboxOverflowEvent, 10)
+		block     = make(chan struct{})
+	)
+	sub := e.SpawnFunc(func(c *Context) {
+		if msg, ok := c.Message().(InboxOverflowEvent); ok {
+			overflows <- msg
+		}
+	}, "sub")
+	e.Subscribe(sub)
+	time.Sleep(time.Millisecond * 10)
+
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(int); ok {
+			<-block
+		}
+	}, "slow", WithInboxSize(1), WithOverflowPolicy(OverflowDropNewest))
+	defer close(block)
+	for i := 0; i < 3; i++ {
+		e.Send(pid, i)
+	}
+	select {
+	case evt := <-overflows:
+		require.True(t, evt.PID.Equals(pid))
+		require.Equal(t, OverflowDropNewest, evt.Policy)
+	case <-time.After(time.Second):
+		t.Fatal("expected an InboxOverflowEvent")
+	}
+}
+
+type urgentMsg struct{}
+
+func (urgentMsg) PriorityMessage() {}
+
+func TestInboxPriorityLane(t *testing.T) {
+	var (
+		inbox    = NewInbox(10)
+		received = make(chan any, 200)
+	)
+	for i := 0; i < 100; i++ {
+		inbox.Send(Envelope{Msg: i})
+	}
+	inbox.Send(Envelope{Msg: urgentMsg{}})
+	inbox.Send(Envelope{Msg: poisonPill{graceful: true}})
+	inbox.Start(MockProcesser{
+		processFunc: func(envelopes []Envelope) {
+			for _, e := range envelopes {
+				received <- e.Msg
+			}
+		},
+	})
+	require.Equal(t, urgentMsg{}, <-received)
+	for i := 0; i < 100; i++ {
+		require.Equal(t, i, <-received)
+	}
+	// a graceful poison pill waits for all the messages before it.
+	_, ok := (<-received).(poisonPill)
+	require.True(t, ok)
+	inbox.Stop()
+}
+
+func TestStopSkipsQueuedMessages(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		processed int32
+		block     = make(chan struct{})
+	)
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(int); ok {
+			<-block
+			atomic.AddInt32(&processed, 1)
+		}
+	}, "busy")
+	for i := 0; i < 100000; i++ {
+		e.Send(pid, i)
+	}
+	ctx := e.Stop(pid)
+	close(block)
+	<-ctx.Done()
+	// the stop is handled right after the message the actor was blocked on.
+	require.LessOrEqual(t, atomic.LoadInt32(&processed), int32(1))
+}
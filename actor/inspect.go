@@ -0,0 +1,90 @@
+package actor
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// ActorInfo is a snapshot of a process, returned by Engine.Inspect.
+type ActorInfo struct {
+	PID *PID
+	// Parent is nil for top-level processes.
+	Parent   *PID
+	Children []*PID
+	// InboxLen is the number of messages waiting in the inbox.
+	InboxLen int
+	// Processed is the number of messages received by the actor.
+	Processed uint64
+	Restarts  int32
+	Uptime    time.Duration
+}
+
+// Inspect returns a snapshot of all the processes of the engine whose ID
+// starts with the given prefix, sorted by ID. An empty prefix returns all the
+// processes. Only the PID is set for processes that are not actors, such as
+// responses.
+func (e *Engine) Inspect(prefix string) []ActorInfo {
+	var procs []Processer
+	e.Registry.each(func(id string, proc Processer) {
+		if strings.HasPrefix(id, prefix) {
+			procs = append(procs, proc)
+		}
+	})
+
+	now := e.clock.Now()
+	infos := make([]ActorInfo, 0, len(procs))
+	for _, proc := range procs {
+		infos = append(infos, inspect(proc, now))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].PID.ID < infos[j].PID.ID
+	})
+	return infos
+}
+
+// InboxLens calls fn with the number of messages waiting in the inbox of each
+// actor of the engine, other processes such as responses are skipped. Unlike
+// Inspect it doesn't take a snapshot of the processes, which keeps it cheap
+// enough to call on every metrics scrape. The lengths are collected first,
+// fn is called once the registry is unlocked.
+func (e *Engine) InboxLens(fn func(pid *PID, n int)) {
+	type inboxLen struct {
+		pid *PID
+		n   int
+	}
+	var lens []inboxLen
+	e.Registry.each(func(_ string, proc Processer) {
+		p, ok := proc.(*process)
+		if !ok {
+			return
+		}
+		n := 0
+		if inbox, ok := p.inbox.(*Inbox); ok {
+			n = int(inbox.Len())
+		}
+		lens = append(lens, inboxLen{pid: p.PID(), n: n})
+	})
+	for _, l := range lens {
+		fn(l.pid, l.n)
+	}
+}
+
+func inspect(proc Processer, now time.Time) ActorInfo {
+	info := ActorInfo{PID: proc.PID()}
+	p, ok := proc.(*process)
+	if !ok {
+		return info
+	}
+	if p.context.parentCtx != nil {
+		info.Parent = p.context.parentCtx.pid
+	}
+	info.Children = p.context.Children()
+	if inbox, ok := p.inbox.(*Inbox); ok {
+		info.InboxLen = int(inbox.Len())
+	}
+	info.Processed = p.processed.Load()
+	info.Restarts = p.restarts.Load()
+	info.Uptime = now.Sub(p.spawnedAt)
+	return info
+}
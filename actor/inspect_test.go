@@ -0,0 +1,95 @@
+package actor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		blocking = make(chan struct{})
+		received = make(chan struct{})
+		children = make(chan *PID, 1)
+	)
+	defer close(blocking)
+	parent := e.SpawnFunc(func(c *Context) {
+		switch c.Message().(type) {
+		case Started:
+			children <- c.SpawnChildFunc(func(c *Context) {}, "child")
+		case string:
+			received <- struct{}{}
+			<-blocking
+		case int:
+			panic("restart")
+		}
+	}, "parent", WithRestartDelay(0))
+	child := <-children
+
+	e.Send(parent, 1)
+	e.Send(parent, "block")
+	<-received
+	e.Send(parent, "queued")
+	e.Send(parent, "queued")
+
+	infos := e.Inspect("parent")
+	// the parent and the children spawned before and after the restart.
+	require.Len(t, infos, 3)
+	info := infos[0]
+	assert.True(t, info.PID.Equals(parent))
+	assert.Nil(t, info.Parent)
+	// the child spawned by the restarted actor is the second child.
+	assert.Len(t, info.Children, 2)
+	assert.Equal(t, 2, info.InboxLen)
+	assert.Equal(t, int32(1), info.Restarts)
+	// the int and the first string.
+	assert.Equal(t, uint64(2), info.Processed)
+	assert.Greater(t, info.Uptime, time.Duration(0))
+
+	var childInfo *ActorInfo
+	for _, info := range infos {
+		if info.PID.Equals(child) {
+			childInfo = &info
+		}
+	}
+	require.NotNil(t, childInfo)
+	assert.True(t, childInfo.Parent.Equals(parent))
+
+	assert.NotEmpty(t, e.Inspect(""))
+	assert.Empty(t, e.Inspect("nope"))
+}
+
+func TestInboxLens(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		blocking = make(chan struct{})
+		received = make(chan struct{})
+	)
+	defer close(blocking)
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			received <- struct{}{}
+			<-blocking
+		}
+	}, "blocked")
+	e.Send(pid, "block")
+	<-received
+	e.Send(pid, "queued")
+	e.Send(pid, "queued")
+	// a pending request isn't an actor.
+	resp := e.Request(e.SpawnFunc(func(c *Context) {}, "silent"), "ping", time.Hour)
+	defer resp.Cancel()
+
+	lens := make(map[string]int)
+	e.InboxLens(func(pid *PID, n int) {
+		lens[pid.ID] = n
+	})
+	assert.Equal(t, 2, lens[pid.ID])
+	assert.NotContains(t, lens, resp.PID().ID)
+	assert.Len(t, lens, len(e.Inspect(""))-1)
+}
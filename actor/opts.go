@@ -2,6 +2,8 @@ package actor
 
 import (
 	"context"
+	"math"
+	"math/rand"
 	"time"
 )
 
@@ -10,21 +12,74 @@ const (
 	defaultMaxRestarts = 3
 )
 
-var defaultRestartDelay = 500 * time.Millisecond
+var (
+	defaultRestartDelay = 500 * time.Millisecond
+	defaultBlockTimeout = time.Second
+)
 
 type ReceiveFunc = func(*Context)
 
 type MiddlewareFunc = func(ReceiveFunc) ReceiveFunc
 
+// SendFunc sends the given envelope to the given PID.
+type SendFunc = func(pid *PID, env Envelope)
+
+// SendMiddlewareFunc wraps the sends of an actor, see WithSendMiddleware.
+type SendMiddlewareFunc = func(SendFunc) SendFunc
+
+// RestartPolicy controls the delay between restarts and the window in which
+// the maximum restarts are counted. The zero value restarts after a fixed
+// RestartDelay and counts MaxRestarts over the whole lifetime of the actor.
+type RestartPolicy struct {
+	// Within is the sliding window in which MaxRestarts is counted.
+	// Zero counts every restart over the lifetime of the actor.
+	Within time.Duration
+	// Multiplier grows the RestartDelay exponentially with every restart
+	// inside the window. Without a window, the delay grows with every
+	// restart until the actor runs for longer than its last delay without
+	// crashing. Values <= 1 keep the delay fixed.
+	Multiplier float64
+	// MaxDelay caps the computed delay. Zero means no cap.
+	MaxDelay time.Duration
+	// Jitter randomly shortens the delay by up to the given fraction (0-1),
+	// so actors that crashed together do not restart at the same time.
+	Jitter float64
+}
+
+// delay returns the delay before the next restart given the base delay
+// and the number of restarts that already happened.
+func (rp RestartPolicy) delay(base time.Duration, restarts int32) time.Duration {
+	d := float64(base)
+	if rp.Multiplier > 1 {
+		d *= math.Pow(rp.Multiplier, float64(restarts))
+	}
+	if rp.MaxDelay > 0 && d > float64(rp.MaxDelay) {
+		d = float64(rp.MaxDelay)
+	}
+	if rp.Jitter > 0 {
+		d -= d * math.Min(rp.Jitter, 1) * rand.Float64()
+	}
+	return time.Duration(d)
+}
+
 type Opts struct {
-	Producer     Producer
-	Kind         string
-	ID           string
-	MaxRestarts  int32
-	RestartDelay time.Duration
-	InboxSize    int
-	Middleware   []MiddlewareFunc
-	Context      context.Context
+	Producer       Producer
+	Kind           string
+	ID             string
+	MaxRestarts    int32
+	RestartDelay   time.Duration
+	RestartPolicy  RestartPolicy
+	InboxSize      int
+	Overflow       OverflowPolicy
+	BlockTimeout   time.Duration
+	Middleware     []MiddlewareFunc
+	SendMiddleware []SendMiddlewareFunc
+	Context        context.Context
+	Supervisor     SupervisorStrategy
+	ReceiveTimeout time.Duration
+	Scheduler      Scheduler
+	Throughput     int
+	InboxStore     InboxStore
 }
 
 type OptFunc func(*Opts)
@@ -36,8 +91,10 @@ func DefaultOpts(p Producer) Opts {
 		Producer:     p,
 		MaxRestarts:  defaultMaxRestarts,
 		InboxSize:    defaultInboxSize,
+		BlockTimeout: defaultBlockTimeout,
 		RestartDelay: defaultRestartDelay,
 		Middleware:   []MiddlewareFunc{},
+		Supervisor:   DefaultSupervisorStrategy,
 	}
 }
 
@@ -53,26 +110,117 @@ func WithMiddleware(mw ...MiddlewareFunc) OptFunc {
 	}
 }
 
+// WithSendMiddleware wraps the messages the actor sends with Context.Send,
+// SendWithHeaders, Forward, Respond, Request and SendAfter. A middleware may
+// change the envelope or the target, or not call next to drop the message.
+func WithSendMiddleware(mw ...SendMiddlewareFunc) OptFunc {
+	return func(opts *Opts) {
+		opts.SendMiddleware = append(opts.SendMiddleware, mw...)
+	}
+}
+
 func WithRestartDelay(d time.Duration) OptFunc {
 	return func(opts *Opts) {
 		opts.RestartDelay = d
 	}
 }
 
+// WithRestartPolicy sets the backoff and the restart window of the actor.
+// The RestartDelay is used as the initial delay of the backoff.
+//
+//	WithRestartPolicy(RestartPolicy{
+//		Within:     time.Minute, // max restarts per minute
+//		Multiplier: 2,
+//		MaxDelay:   time.Second * 30,
+//		Jitter:     0.2,
+//	})
+func WithRestartPolicy(policy RestartPolicy) OptFunc {
+	return func(opts *Opts) {
+		opts.RestartPolicy = policy
+	}
+}
+
 func WithInboxSize(size int) OptFunc {
 	return func(opts *Opts) {
 		opts.InboxSize = size
 	}
 }
 
+// WithOverflowPolicy bounds the inbox to the size given by WithInboxSize
+// and sets what happens with the messages that do not fit. Each dropped
+// message is published as an InboxOverflowEvent.
+func WithOverflowPolicy(policy OverflowPolicy) OptFunc {
+	return func(opts *Opts) {
+		opts.Overflow = policy
+	}
+}
+
+// WithBlockTimeout sets how long a sender is blocked on a full inbox with
+// the OverflowBlock policy, before the message is dropped.
+func WithBlockTimeout(d time.Duration) OptFunc {
+	return func(opts *Opts) {
+		opts.BlockTimeout = d
+	}
+}
+
 func WithMaxRestarts(n int) OptFunc {
 	return func(opts *Opts) {
 		opts.MaxRestarts = int32(n)
 	}
 }
 
+// WithReceiveTimeout makes the actor receive a ReceiveTimeout message each
+// time it did not receive any message for the given duration.
+func WithReceiveTimeout(d time.Duration) OptFunc {
+	return func(opts *Opts) {
+		opts.ReceiveTimeout = d
+	}
+}
+
+// WithScheduler sets the scheduler that runs the inbox of the actor. By
+// default each activation of the inbox runs on a new goroutine. See
+// NewWorkerPoolScheduler and NewPinnedScheduler.
+func WithScheduler(s Scheduler) OptFunc {
+	return func(opts *Opts) {
+		opts.Scheduler = s
+	}
+}
+
+// WithDurableInbox persists every message sent to the actor in the given
+// store before it is added to the inbox, and removes it once the actor
+// received it. A stashed message is removed once it is received again after
+// UnstashAll. The message the actor panicked on is received again first
+// after a restart, and only removed when the supervisor resumes the actor,
+// which skips it. Hence a message that always panics is received until the
+// actor exceeds its max restarts. The messages that were not
+// received when the actor stopped, exceeded its max restarts or when the
+// engine went down, are received first when an actor with the same kind and
+// ID is spawned again. Messages are received at least once.
+func WithDurableInbox(store InboxStore) OptFunc {
+	return func(opts *Opts) {
+		opts.InboxStore = store
+	}
+}
+
+// WithThroughput sets the number of message batches the actor processes
+// before it yields to the other actors, overriding the throughput of the
+// scheduler.
+func WithThroughput(n int) OptFunc {
+	return func(opts *Opts) {
+		opts.Throughput = n
+	}
+}
+
 func WithID(id string) OptFunc {
 	return func(opts *Opts) {
 		opts.ID = id
 	}
 }
+
+// WithSupervisor sets the strategy that decides what happens when the
+// spawned process panics. Parents typically pass it to SpawnChild.
+func WithSupervisor(s SupervisorStrategy) OptFunc {
+	return func(opts *Opts) {
+		opts.Supervisor = s
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log/slog"
 	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/DataDog/gostackparse"
@@ -14,6 +16,18 @@ import (
 type Envelope struct {
 	Msg    any
 	Sender *PID
+	// Trace is the SpanContext of the sender, it is empty for messages that
+	// are not part of a trace.
+	Trace SpanContext
+	// Headers holds metadata of the message, like correlation IDs. They must
+	// not be modified once the message is sent.
+	Headers map[string]string
+	// seq is the sequence number of the message in the durable inbox.
+	seq uint64
+	// noWait makes a send to a full inbox with the OverflowBlock policy
+	// wait on its own goroutine instead of blocking the sender. It is set
+	// for the sends of the timers, which share a single goroutine.
+	noWait bool
 }
 
 // Processer is an interface the abstracts the way a process behaves.
@@ -31,19 +45,52 @@ type process struct {
 	inbox    Inboxer
 	context  *Context
 	pid      *PID
-	restarts int32
+	restarts atomic.Int32
 	mbuffer  []Envelope
+	// restartTimes holds the restarts inside the window of the restart policy.
+	restartTimes []time.Time
+	// backoffRestarts, lastRestart and lastDelay track the backoff of a
+	// restart policy without a window.
+	backoffRestarts int32
+	lastRestart     time.Time
+	lastDelay       time.Duration
+	// spawnedAt and processed are reported by Engine.Inspect.
+	spawnedAt time.Time
+	processed atomic.Uint64
+	// durableMu serializes the appends to the durable inbox.
+	durableMu sync.Mutex
 }
 
 func newProcess(e *Engine, opts Opts) *process {
 	pid := NewPID(e.address, opts.Kind+pidSeparator+opts.ID)
+	if len(e.middleware) > 0 && !e.isSystem(pid) {
+		opts.Middleware = append(e.middleware[:len(e.middleware):len(e.middleware)], opts.Middleware...)
+	}
 	ctx := newContext(opts.Context, e, pid)
+	for i := len(opts.SendMiddleware) - 1; i >= 0; i-- {
+		ctx.send = opts.SendMiddleware[i](ctx.send)
+	}
+	inbox := NewInbox(opts.InboxSize)
+	if opts.Scheduler != nil {
+		inbox.scheduler = opts.Scheduler
+	} else if e.scheduler != nil {
+		inbox.scheduler = e.scheduler
+	}
+	inbox.clock = e.clock
+	inbox.throughput = opts.Throughput
 	p := &process{
-		pid:     pid,
-		inbox:   NewInbox(opts.InboxSize),
-		Opts:    opts,
-		context: ctx,
-		mbuffer: nil,
+		pid:       pid,
+		inbox:     inbox,
+		Opts:      opts,
+		context:   ctx,
+		mbuffer:   nil,
+		spawnedAt: e.clock.Now(),
+	}
+	if opts.Overflow != OverflowGrow {
+		inbox.setOverflow(opts.Overflow, opts.BlockTimeout, p.overflow)
+	}
+	if opts.InboxStore != nil {
+		p.loadPending()
 	}
 	return p
 }
@@ -70,14 +117,14 @@ func (p *process) Invoke(msgs []Envelope) {
 		// If we recovered, we buffer up all the messages that we could not process
 		// so we can retry them on the next restart.
 		if v := recover(); v != nil {
-			p.context.message = Stopped{}
-			p.context.receiver.Receive(p.context)
-
-			p.mbuffer = make([]Envelope, nmsg-nproc)
-			for i := 0; i < nmsg-nproc; i++ {
-				p.mbuffer[i] = msgs[i+nproc]
+			// the message of the durable inbox we failed on, if any, stays
+			// in the store until the supervisor decided what to do with it.
+			var failed Envelope
+			if c := p.context; c.seq != 0 {
+				failed = Envelope{Msg: c.message, Sender: c.sender, Trace: c.span, Headers: c.headers, seq: c.seq}
+				c.seq = 0
 			}
-			p.tryRestart(v)
+			p.handleFailure(v, failed, msgs[nproc:nmsg])
 		}
 	}()
 
@@ -91,48 +138,98 @@ func (p *process) Invoke(msgs []Envelope) {
 				msgsToProcess := msgs[processed:]
 				for _, m := range msgsToProcess {
 					p.invokeMsg(m)
+					p.ackCurrent()
 				}
 			}
 			p.cleanup(pill.cancel)
 			return
 		}
 		p.invokeMsg(msg)
+		p.ackCurrent()
 		processed++
+		// Unstashed messages are processed before the rest of the batch, and
+		// the priority messages that arrived in the meantime before those.
+		rest := msgs[i+1:]
+		if unstashed := p.context.takeUnstashed(); len(unstashed) > 0 {
+			rest = append(unstashed, rest...)
+		}
+		if in, ok := p.inbox.(*Inbox); ok {
+			if prio := in.takePriority(); len(prio) > 0 {
+				rest = append(prio, rest...)
+			}
+		}
+		if len(rest) != len(msgs)-i-1 {
+			msgs = rest
+			nmsg = len(msgs)
+			i, nproc, processed = -1, 0, 0
+		}
 	}
 }
 
 func (p *process) invokeMsg(msg Envelope) {
+	p.context.seq = msg.seq
+	switch m := msg.Msg.(type) {
 	// suppress poison pill messages here. they're private to the actor engine.
-	if _, ok := msg.Msg.(poisonPill); ok {
+	case poisonPill:
 		return
+	// a sibling failed and our supervisor wants us to restart with it.
+	case restartProcess:
+		panic(m)
+	// a child escalated its failure, hence we fail with the same reason.
+	case childFailure:
+		panic(m.reason)
+	// the response of a RequestAsync arrived.
+	case continuation:
+		p.context.message = nil
+		p.context.sender = nil
+		p.context.span = msg.Trace
+		p.context.headers = nil
+		m.fn(m.resp, m.err)
+		return
+	}
+	if p.context.receiveTimer != nil {
+		if _, ok := msg.Msg.(ReceiveTimeout); !ok {
+			p.context.receiveTimer.touch()
+		}
 	}
+	p.processed.Add(1)
 	p.context.message = msg.Msg
 	p.context.sender = msg.Sender
-	recv := p.context.receiver
+	p.context.span = msg.Trace
+	p.context.headers = msg.Headers
+	recv := p.context.behaviour()
 	if len(p.Opts.Middleware) > 0 {
-		applyMiddleware(recv.Receive, p.Opts.Middleware...)(p.context)
+		applyMiddleware(recv, p.Opts.Middleware...)(p.context)
 	} else {
-		recv.Receive(p.context)
+		recv(p.context)
 	}
 }
 
 func (p *process) Start() {
 	recv := p.Producer()
 	p.context.receiver = recv
+	p.context.behaviours = nil
+	if p.ReceiveTimeout > 0 || p.context.receiveTimer != nil {
+		p.context.SetReceiveTimeout(p.ReceiveTimeout)
+	}
 	defer func() {
 		if v := recover(); v != nil {
-			p.context.message = Stopped{}
-			p.context.receiver.Receive(p.context)
-			p.tryRestart(v)
+			p.handleFailure(v, Envelope{}, nil)
 		}
 	}()
 	p.context.message = Initialized{}
+	p.context.span = SpanContext{}
+	p.context.headers = nil
 	applyMiddleware(recv.Receive, p.Opts.Middleware...)(p.context)
-	p.context.engine.BroadcastEvent(ActorInitializedEvent{PID: p.pid, Timestamp: time.Now()})
+	p.context.engine.BroadcastEvent(ActorInitializedEvent{PID: p.pid, Timestamp: p.context.engine.clock.Now()})
 
 	p.context.message = Started{}
 	applyMiddleware(recv.Receive, p.Opts.Middleware...)(p.context)
-	p.context.engine.BroadcastEvent(ActorStartedEvent{PID: p.pid, Timestamp: time.Now()})
+	p.context.engine.BroadcastEvent(ActorStartedEvent{PID: p.pid, Timestamp: p.context.engine.clock.Now()})
+	// Messages unstashed while starting are invoked first.
+	if unstashed := p.context.takeUnstashed(); len(unstashed) > 0 {
+		p.mbuffer = append(unstashed, p.mbuffer...)
+	}
 	// If we have messages in our buffer, invoke them.
 	if len(p.mbuffer) > 0 {
 		p.Invoke(p.mbuffer)
@@ -142,7 +239,74 @@ func (p *process) Start() {
 	p.inbox.Start(p)
 }
 
-func (p *process) tryRestart(v any) {
+// handleFailure asks the supervisor strategy of the process what to do with
+// the recovered panic value v. The messages that were not processed yet are
+// either kept for the next restart or processed right away on resume. failed
+// is the message of the durable inbox the actor failed on, it is skipped when
+// the actor resumes, received again first when it restarts and kept in the
+// store when it stops.
+func (p *process) handleFailure(v any, failed Envelope, unprocessed []Envelope) {
+	directive := RestartDirective
+	sibling := false
+	switch msg := v.(type) {
+	case *InternalError:
+		// InternalError is handled by tryRestart, keep the old behaviour.
+	case restartProcess:
+		v = msg.reason
+		sibling = true
+	default:
+		directive = p.Supervisor.HandleFailure(p.context, v)
+	}
+
+	switch directive {
+	case ResumeDirective:
+		p.context.engine.BroadcastEvent(ActorResumedEvent{
+			PID:        p.pid,
+			Timestamp:  p.context.engine.clock.Now(),
+			Stacktrace: cleanTrace(debug.Stack()),
+			Reason:     v,
+		})
+		p.ack(failed.seq)
+		if len(unprocessed) > 0 {
+			p.Invoke(unprocessed)
+		}
+		// the inbox is not started yet when we failed during Start.
+		p.inbox.Start(p)
+	case StopDirective:
+		p.cleanup(nil)
+	case EscalateDirective:
+		parent := p.context.parentCtx
+		p.cleanup(nil)
+		if parent != nil {
+			p.context.engine.SendLocal(parent.pid, childFailure{child: p.pid, reason: v}, p.pid)
+		}
+	default:
+		p.context.message = Stopped{}
+		p.context.receiver.Receive(p.context)
+
+		// unprocessed is nil when we failed during Start, the buffer of
+		// the previous failure is then still waiting to be processed.
+		if unprocessed != nil {
+			p.mbuffer = make([]Envelope, len(unprocessed))
+			copy(p.mbuffer, unprocessed)
+		}
+		if failed.seq != 0 {
+			p.mbuffer = append([]Envelope{failed}, p.mbuffer...)
+		}
+		// Stashed messages survive the restart, they are delivered again
+		// in front of the buffered messages.
+		if stashed := p.context.takeStash(); len(stashed) > 0 {
+			p.mbuffer = append(stashed, p.mbuffer...)
+		}
+		if sibling {
+			p.restartWithSibling(v, cleanTrace(debug.Stack()))
+			return
+		}
+		p.tryRestart(v, cleanTrace(debug.Stack()))
+	}
+}
+
+func (p *process) tryRestart(v any, stackTrace []byte) {
 	// InternalError does not take the maximum restarts into account.
 	// For now, InternalError is getting triggered when we are dialing
 	// a remote node. By doing this, we can keep dialing until it comes
@@ -150,37 +314,106 @@ func (p *process) tryRestart(v any) {
 	// node never comes back up again?
 	if msg, ok := v.(*InternalError); ok {
 		slog.Error(msg.From, "err", msg.Err)
-		time.Sleep(p.Opts.RestartDelay)
-		p.Start()
+		p.restartAfter(p.Opts.RestartDelay)
 		return
 	}
-	stackTrace := cleanTrace(debug.Stack())
+	now := p.context.engine.clock.Now()
+	// Only the restarts inside the window of the restart policy count
+	// towards the max restarts. Without a window all restarts count.
+	recent := p.restarts.Load()
+	if p.RestartPolicy.Within > 0 {
+		p.restartTimes = pruneRestartTimes(p.restartTimes, now.Add(-p.RestartPolicy.Within))
+		recent = int32(len(p.restartTimes))
+	}
 	// If we reach the max restarts, we shutdown the inbox and clean
 	// everything up.
-	if p.restarts == p.MaxRestarts {
+	if recent >= p.MaxRestarts {
 		p.context.engine.BroadcastEvent(ActorMaxRestartsExceededEvent{
 			PID:       p.pid,
-			Timestamp: time.Now(),
+			Timestamp: p.context.engine.clock.Now(),
 		})
 		p.cleanup(nil)
 		return
 	}
 
-	p.restarts++
-	// Restart the process after its restartDelay
+	restarts := p.restarts.Add(1)
+	backoff := recent
+	if p.RestartPolicy.Within > 0 {
+		p.restartTimes = append(p.restartTimes, now)
+	} else {
+		backoff = p.backoff(now)
+	}
+	delay := p.RestartPolicy.delay(p.RestartDelay, backoff)
+	p.lastRestart, p.lastDelay = now, delay
+	// Restart the process after the delay computed by the restart policy.
+	p.context.engine.BroadcastEvent(ActorRestartedEvent{
+		PID:        p.pid,
+		Timestamp:  now,
+		Stacktrace: stackTrace,
+		Reason:     v,
+		Restarts:   restarts,
+		Delay:      delay,
+	})
+	p.restartAfter(delay)
+}
+
+// restartWithSibling restarts the process right away because a sibling
+// failed. The restart is on the failing sibling, hence it doesn't count
+// towards the max restarts of the process nor grows its restart delay.
+func (p *process) restartWithSibling(v any, stackTrace []byte) {
 	p.context.engine.BroadcastEvent(ActorRestartedEvent{
 		PID:        p.pid,
-		Timestamp:  time.Now(),
+		Timestamp:  p.context.engine.clock.Now(),
 		Stacktrace: stackTrace,
 		Reason:     v,
-		Restarts:   p.restarts,
+		Restarts:   p.restarts.Load(),
 	})
-	time.Sleep(p.Opts.RestartDelay)
 	p.Start()
 }
 
+// backoff returns the number of restarts the delay grows with when the
+// restart policy has no window. Those are the restarts since the actor last
+// ran for longer than its previous restart delay without crashing.
+func (p *process) backoff(now time.Time) int32 {
+	if uptime := now.Sub(p.lastRestart) - p.lastDelay; uptime > p.lastDelay {
+		p.backoffRestarts = 0
+	}
+	n := p.backoffRestarts
+	p.backoffRestarts++
+	return n
+}
+
+// restartAfter starts the process again after the given delay. With the
+// system clock it sleeps on the goroutine of the process. Otherwise the
+// sleep would block the clock, hence the inbox is paused and the process is
+// started by a timer on the scheduler of the inbox.
+func (p *process) restartAfter(d time.Duration) {
+	e := p.context.engine
+	if isSystemClock(e.clock) {
+		time.Sleep(d)
+		p.Start()
+		return
+	}
+	inbox := p.inbox.(*Inbox)
+	inbox.pause()
+	e.timers.start(e.timers.newTimer(func() {
+		inbox.scheduler.Schedule(p.Start)
+	}, 0, nil), d)
+}
+
+// pruneRestartTimes drops all restart times that happened before since.
+func pruneRestartTimes(times []time.Time, since time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(since) {
+		i++
+	}
+	return times[i:]
+}
+
 func (p *process) cleanup(cancel context.CancelFunc) {
-	defer cancel()
+	if cancel != nil {
+		defer cancel()
+	}
 
 	if p.context.parentCtx != nil {
 		p.context.parentCtx.children.Delete(p.pid.ID)
@@ -194,16 +427,51 @@ func (p *process) cleanup(cancel context.CancelFunc) {
 	}
 
 	p.inbox.Stop()
+	if p.context.receiveTimer != nil {
+		p.context.receiveTimer.stop()
+	}
+	p.context.stopTimers()
 	p.context.engine.Registry.Remove(p.pid)
 	p.context.message = Stopped{}
 	applyMiddleware(p.context.receiver.Receive, p.Opts.Middleware...)(p.context)
 
-	p.context.engine.BroadcastEvent(ActorStoppedEvent{PID: p.pid, Timestamp: time.Now()})
+	p.context.engine.BroadcastEvent(ActorStoppedEvent{PID: p.pid, Timestamp: p.context.engine.clock.Now()})
+}
+
+// overflow is called by the inbox for each message it dropped.
+func (p *process) overflow(msg Envelope) {
+	p.ack(msg.seq)
+	p.context.engine.BroadcastEvent(InboxOverflowEvent{
+		PID:     p.pid,
+		Message: msg.Msg,
+		Sender:  msg.Sender,
+		Policy:  p.Overflow,
+	})
+	if p.Overflow == OverflowDeadLetter {
+		p.context.engine.BroadcastEvent(DeadLetterEvent{
+			Target:  p.pid,
+			Message: msg.Msg,
+			Sender:  msg.Sender,
+			Headers: msg.Headers,
+			Trace:   msg.Trace,
+		})
+	}
 }
 
 func (p *process) PID() *PID { return p.pid }
 func (p *process) Send(_ *PID, msg any, sender *PID) {
-	p.inbox.Send(Envelope{Msg: msg, Sender: sender})
+	p.sendEnvelope(Envelope{Msg: msg, Sender: sender})
+}
+
+func (p *process) sendEnvelope(env Envelope) {
+	if p.InboxStore != nil && isDurable(env.Msg) {
+		// the inbox may block under OverflowBlock, hence it's not sent to
+		// under the lock.
+		p.durableMu.Lock()
+		p.persist(&env)
+		p.durableMu.Unlock()
+	}
+	p.inbox.Send(env)
 }
 func (p *process) Shutdown() {
 	p.cleanup(nil)
@@ -117,3 +117,71 @@ func Test_StartupMessages(t *testing.T) {
 		return
 	}
 }
+
+func TestRestartPolicyDelay(t *testing.T) {
+	policy := RestartPolicy{
+		Multiplier: 2,
+		MaxDelay:   time.Millisecond * 350,
+	}
+	base := time.Millisecond * 100
+	require.Equal(t, base, policy.delay(base, 0))
+	require.Equal(t, base*2, policy.delay(base, 1))
+	require.Equal(t, base*4-base/2, policy.delay(base, 2))
+	require.Equal(t, base, RestartPolicy{}.delay(base, 10))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := policy.delay(base, 1)
+		require.True(t, d > base && d <= base*2)
+	}
+}
+
+func TestRestartWindow(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		restarted = make(chan ActorRestartedEvent, 10)
+		exceeded  = make(chan struct{}, 1)
+	)
+	sub := e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case ActorRestartedEvent:
+			restarted <- msg
+		case ActorMaxRestartsExceededEvent:
+			exceeded <- struct{}{}
+		}
+	}, "sub")
+	e.Subscribe(sub)
+	time.Sleep(time.Millisecond * 10)
+
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			panic("crash")
+		}
+	}, "foo", WithMaxRestarts(1), WithRestartDelay(time.Millisecond), WithRestartPolicy(RestartPolicy{
+		Within: time.Millisecond * 50,
+	}))
+
+	// Each crash happens outside of the window of the previous one, hence
+	// the actor keeps getting restarted although it only allows one restart.
+	for i := 0; i < 3; i++ {
+		e.Send(pid, "crash")
+		select {
+		case evt := <-restarted:
+			require.Equal(t, int32(i+1), evt.Restarts)
+			require.Equal(t, time.Millisecond, evt.Delay)
+		case <-time.After(time.Second):
+			t.Fatal("actor was not restarted")
+		}
+		time.Sleep(time.Millisecond * 100)
+	}
+
+	// Two crashes inside the window will exceed the max restarts.
+	e.Send(pid, "crash")
+	e.Send(pid, "crash")
+	select {
+	case <-exceeded:
+	case <-time.After(time.Second):
+		t.Fatal("max restarts were not exceeded")
+	}
+}
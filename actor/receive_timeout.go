@@ -0,0 +1,69 @@
+package actor
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// receiveTimer fires when the actor has not received a message for the
+// configured timeout. It is backed by a single runtime timer that is only
+// re-armed when it fires, so receiving a message costs a single store.
+type receiveTimer struct {
+	clock   Clock
+	timeout atomic.Int64
+	last    atomic.Int64
+	fire    func()
+
+	mu    sync.Mutex
+	timer ClockTimer
+}
+
+func newReceiveTimer(clock Clock, fire func()) *receiveTimer {
+	return &receiveTimer{clock: clock, fire: fire}
+}
+
+// set (re)starts the timer with the given timeout, d <= 0 disables it.
+func (t *receiveTimer) set(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	if d <= 0 {
+		t.timeout.Store(0)
+		return
+	}
+	t.timeout.Store(int64(d))
+	t.last.Store(t.clock.Now().UnixNano())
+	t.timer = t.clock.AfterFunc(d, t.expire)
+}
+
+// touch marks that a message was received.
+func (t *receiveTimer) touch() {
+	if t.timeout.Load() > 0 {
+		t.last.Store(t.clock.Now().UnixNano())
+	}
+}
+
+func (t *receiveTimer) stop() {
+	t.set(0)
+}
+
+func (t *receiveTimer) expire() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	timeout := time.Duration(t.timeout.Load())
+	if t.timer == nil || timeout <= 0 {
+		return
+	}
+	idle := t.clock.Now().Sub(time.Unix(0, t.last.Load()))
+	if idle < timeout {
+		t.timer.Reset(timeout - idle)
+		return
+	}
+	t.fire()
+	t.last.Store(t.clock.Now().UnixNano())
+	t.timer.Reset(timeout)
+}
@@ -2,21 +2,45 @@ package actor
 
 import (
 	"sync"
+
+	"github.com/zeebo/xxh3"
 )
 
 const LocalLookupAddr = "local"
 
+// registryShards is the number of shards of the Registry, a power of two.
+const registryShards = 64
+
+// Registry holds the processes of an engine by the ID of their PID. The
+// processes are spread over shards by the hash of their ID, each guarded by
+// its own lock, so sends, spawns and stops on different processes rarely
+// contend for the same lock.
 type Registry struct {
+	shards [registryShards]registryShard
+	engine *Engine
+}
+
+type registryShard struct {
 	mu     sync.RWMutex
 	lookup map[string]Processer
-	engine *Engine
+	// pad the shard to a cache line, so the locks of neighbouring shards
+	// are not invalidated together.
+	_ [32]byte
 }
 
 func newRegistry(e *Engine) *Registry {
-	return &Registry{
-		lookup: make(map[string]Processer, 1024),
-		engine: e,
+	r := &Registry{engine: e}
+	for i := range r.shards {
+		r.shards[i].lookup = make(map[string]Processer, 1024/registryShards)
 	}
+	return r
+}
+
+// shard returns the shard of the given ID. The hash is the one of
+// PID.LookupKey without the address, which is the same for all the
+// processes of the registry.
+func (r *Registry) shard(id string) *registryShard {
+	return &r.shards[xxh3.HashString(id)&(registryShards-1)]
 }
 
 // GetPID returns the process id associated for the given kind and its id.
@@ -31,9 +55,10 @@ func (r *Registry) GetPID(kind, id string) *PID {
 
 // Remove removes the given PID from the registry.
 func (r *Registry) Remove(pid *PID) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	delete(r.lookup, pid.ID)
+	s := r.shard(pid.ID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.lookup, pid.ID)
 }
 
 // get returns the processer for the given PID, if it exists.
@@ -43,29 +68,40 @@ func (r *Registry) get(pid *PID) Processer {
 	if pid == nil {
 		return nil
 	}
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	if proc, ok := r.lookup[pid.ID]; ok {
-		return proc
-	}
-	return nil // didn't find the processer
+	return r.getByID(pid.ID)
 }
 
 func (r *Registry) getByID(id string) Processer {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-	return r.lookup[id]
+	s := r.shard(id)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lookup[id]
 }
 
 func (r *Registry) add(proc Processer) {
-	r.mu.Lock()
 	id := proc.PID().ID
-	if _, ok := r.lookup[id]; ok {
-		r.mu.Unlock()
+	s := r.shard(id)
+	s.mu.Lock()
+	if _, ok := s.lookup[id]; ok {
+		s.mu.Unlock()
 		r.engine.BroadcastEvent(ActorDuplicateIdEvent{PID: proc.PID()})
 		return
 	}
-	r.lookup[id] = proc
-	r.mu.Unlock()
+	s.lookup[id] = proc
+	s.mu.Unlock()
 	proc.Start()
 }
+
+// each calls fn for all the processes of the registry, one shard at a time.
+// The processes added or removed meanwhile may or may not be visited. fn must
+// not add or remove processes.
+func (r *Registry) each(fn func(id string, proc Processer)) {
+	for i := range r.shards {
+		s := &r.shards[i]
+		s.mu.RLock()
+		for id, proc := range s.lookup {
+			fn(id, proc)
+		}
+		s.mu.RUnlock()
+	}
+}
@@ -1,6 +1,9 @@
 package actor
 
 import (
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -28,3 +31,115 @@ func TestGetRemoveAdd(t *testing.T) {
 	proc = reg.get(eproc.PID())
 	assert.Nil(t, proc)
 }
+
+type idProc struct {
+	fooProc
+	pid *PID
+}
+
+func (p idProc) PID() *PID { return p.pid }
+
+func TestRegistryConcurrent(t *testing.T) {
+	e, _ := NewEngine(NewEngineConfig())
+	reg := newRegistry(e)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				pid := NewPID(LocalLookupAddr, "proc/"+strconv.Itoa(g)+"-"+strconv.Itoa(i))
+				reg.add(idProc{pid: pid})
+				assert.Equal(t, pid, reg.get(pid).PID())
+				if i%2 == 0 {
+					reg.Remove(pid)
+					assert.Nil(t, reg.get(pid))
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	n := 0
+	reg.each(func(id string, proc Processer) {
+		assert.Equal(t, id, proc.PID().ID)
+		n++
+	})
+	assert.Equal(t, 8*500, n)
+}
+
+// globalRegistry is the Registry before it was sharded, a single map behind
+// a single lock, which the benchmarks use as baseline.
+type globalRegistry struct {
+	mu     sync.RWMutex
+	lookup map[string]Processer
+}
+
+func (r *globalRegistry) get(pid *PID) Processer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lookup[pid.ID]
+}
+
+func (r *globalRegistry) add(proc Processer) {
+	r.mu.Lock()
+	r.lookup[proc.PID().ID] = proc
+	r.mu.Unlock()
+	proc.Start()
+}
+
+func (r *globalRegistry) Remove(pid *PID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.lookup, pid.ID)
+}
+
+type benchRegistry interface {
+	get(*PID) Processer
+	add(Processer)
+	Remove(*PID)
+}
+
+// runRegistryBenchmark runs the benchmark against the global-lock baseline
+// and the sharded Registry. Run it with -cpu 1,4,8,16 to compare the
+// contention of both as the number of CPUs grows.
+func runRegistryBenchmark(b *testing.B, bench func(*testing.B, benchRegistry)) {
+	b.Run("global", func(b *testing.B) {
+		bench(b, &globalRegistry{lookup: make(map[string]Processer, 1024)})
+	})
+	b.Run("sharded", func(b *testing.B) {
+		e, _ := NewEngine(NewEngineConfig())
+		bench(b, newRegistry(e))
+	})
+}
+
+func BenchmarkRegistryGet(b *testing.B) {
+	runRegistryBenchmark(b, func(b *testing.B, reg benchRegistry) {
+		pids := make([]*PID, 1024)
+		for i := range pids {
+			pids[i] = NewPID(LocalLookupAddr, "proc/"+strconv.Itoa(i))
+			reg.add(idProc{pid: pids[i]})
+		}
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				reg.get(pids[i%len(pids)])
+				i++
+			}
+		})
+	})
+}
+
+func BenchmarkRegistryAddRemove(b *testing.B) {
+	runRegistryBenchmark(b, func(b *testing.B, reg benchRegistry) {
+		var next atomic.Int64
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				pid := NewPID(LocalLookupAddr, "proc/"+strconv.FormatInt(next.Add(1), 10))
+				reg.add(idProc{pid: pid})
+				reg.Remove(pid)
+			}
+		})
+	})
+}
@@ -2,48 +2,215 @@ package actor
 
 import (
 	"context"
-	"math"
-	"math/rand"
 	"strconv"
+	"sync"
 	"time"
 )
 
+// Response is the future of a request. It is resolved with the first message
+// that is sent to its PID, or with an error once its timeout is exceeded or it
+// is cancelled. A resolved Response is removed from the Registry right away.
 type Response struct {
 	engine  *Engine
 	pid     *PID
-	result  chan any
 	timeout time.Duration
+	timer   *Timer
+	// inputs are the responses a future returned by WhenAll, WhenAny or
+	// Map is composed of, they are cancelled together with it.
+	inputs []*Response
+
+	once      sync.Once
+	done      chan struct{}
+	mu        sync.Mutex
+	callbacks []func(any, error)
+	result    any
+	err       error
 }
 
+// NewResponse returns a Response that times out after the given timeout once
+// it is added to the Registry of the engine.
 func NewResponse(e *Engine, timeout time.Duration) *Response {
+	r := newFuture(e)
+	r.timeout = timeout
+	r.pid = NewPID(e.address, "response"+pidSeparator+strconv.FormatUint(e.responseID.Add(1), 10))
+	return r
+}
+
+// newFuture returns a Response that is not registered and only resolved by
+// calling complete.
+func newFuture(e *Engine) *Response {
 	return &Response{
-		engine:  e,
-		result:  make(chan any, 1),
-		timeout: timeout,
-		pid:     NewPID(e.address, "response"+pidSeparator+strconv.Itoa(rand.Intn(math.MaxInt32))),
+		engine: e,
+		done:   make(chan struct{}),
 	}
 }
 
+// Result blocks until the Response is resolved. It returns
+// context.DeadlineExceeded when the timeout was exceeded and context.Canceled
+// when the Response was cancelled.
 func (r *Response) Result() (any, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
-	defer func() {
-		cancel()
-		r.engine.Registry.Remove(r.pid)
-	}()
+	<-r.done
+	return r.result, r.err
+}
 
+// Done returns a channel that is closed once the Response is resolved.
+func (r *Response) Done() <-chan struct{} {
+	return r.done
+}
+
+// OnComplete registers a callback that is invoked once the Response is
+// resolved, or right away if it is already resolved. The callback runs on
+// the goroutine that resolved the Response, hence it must not block.
+func (r *Response) OnComplete(fn func(resp any, err error)) {
+	r.mu.Lock()
 	select {
-	case resp := <-r.result:
-		return resp, nil
-	case <-ctx.Done():
-		return nil, ctx.Err()
+	case <-r.done:
+		r.mu.Unlock()
+		fn(r.result, r.err)
+	default:
+		r.callbacks = append(r.callbacks, fn)
+		r.mu.Unlock()
 	}
 }
 
+// Map returns a Response that is resolved with the result of fn applied on
+// the result of r. Errors of r are passed through without calling fn.
+func (r *Response) Map(fn func(resp any) (any, error)) *Response {
+	mapped := newFuture(r.engine)
+	mapped.inputs = []*Response{r}
+	r.OnComplete(func(resp any, err error) {
+		if err != nil {
+			mapped.complete(nil, err)
+			return
+		}
+		mapped.complete(fn(resp))
+	})
+	return mapped
+}
+
+// Cancel resolves the Response with context.Canceled and removes it from
+// the Registry. A response that arrives afterwards ends up as a deadletter.
+// Cancelling a Response returned by WhenAll, WhenAny or Map cancels the
+// responses it is composed of as well.
+func (r *Response) Cancel() {
+	r.complete(nil, context.Canceled)
+	for _, in := range r.inputs {
+		in.Cancel()
+	}
+}
+
+func (r *Response) complete(resp any, err error) {
+	r.once.Do(func() {
+		r.timer.Stop()
+		if r.pid != nil {
+			r.engine.Registry.Remove(r.pid)
+		}
+		r.mu.Lock()
+		r.result, r.err = resp, err
+		close(r.done)
+		callbacks := r.callbacks
+		r.callbacks = nil
+		r.mu.Unlock()
+		for _, fn := range callbacks {
+			fn(resp, err)
+		}
+	})
+}
+
+// WhenAll returns a Response that is resolved with the results of all the
+// given responses, in the same order, once they are all resolved. It fails
+// with the first error of any of them.
+func WhenAll(responses ...*Response) *Response {
+	all := newFuture(engineOf(responses))
+	all.inputs = append([]*Response(nil), responses...)
+	if len(responses) == 0 {
+		all.complete([]any{}, nil)
+		return all
+	}
+	var (
+		mu      sync.Mutex
+		results = make([]any, len(responses))
+		pending = len(responses)
+	)
+	for i, r := range responses {
+		i := i
+		r.OnComplete(func(resp any, err error) {
+			if err != nil {
+				all.complete(nil, err)
+				return
+			}
+			mu.Lock()
+			results[i] = resp
+			pending--
+			done := pending == 0
+			mu.Unlock()
+			if done {
+				all.complete(results, nil)
+			}
+		})
+	}
+	return all
+}
+
+// WhenAny returns a Response that is resolved with the first successful
+// result of the given responses. It fails with the last error when all of
+// them failed.
+func WhenAny(responses ...*Response) *Response {
+	first := newFuture(engineOf(responses))
+	first.inputs = append([]*Response(nil), responses...)
+	if len(responses) == 0 {
+		first.complete(nil, context.Canceled)
+		return first
+	}
+	var (
+		mu      sync.Mutex
+		pending = len(responses)
+	)
+	for _, r := range responses {
+		r.OnComplete(func(resp any, err error) {
+			if err == nil {
+				first.complete(resp, nil)
+				return
+			}
+			mu.Lock()
+			pending--
+			failed := pending == 0
+			mu.Unlock()
+			if failed {
+				first.complete(nil, err)
+			}
+		})
+	}
+	return first
+}
+
+func engineOf(responses []*Response) *Engine {
+	if len(responses) == 0 {
+		return nil
+	}
+	return responses[0].engine
+}
+
 func (r *Response) Send(_ *PID, msg any, _ *PID) {
-	r.result <- msg
+	r.complete(msg, nil)
+}
+
+func (r *Response) PID() *PID { return r.pid }
+func (r *Response) Shutdown() { r.Cancel() }
+
+// Start starts the timeout of the Response, it is called by the Registry.
+func (r *Response) Start() {
+	r.timer = r.engine.timers.newTimer(func() {
+		r.complete(nil, context.DeadlineExceeded)
+	}, 0, nil)
+	r.engine.timers.start(r.timer, r.timeout)
 }
 
-func (r *Response) PID() *PID         { return r.pid }
-func (r *Response) Shutdown()         {}
-func (r *Response) Start()            {}
 func (r *Response) Invoke([]Envelope) {}
+
+// continuation is invoked on the goroutine of the process it is sent to.
+type continuation struct {
+	fn   func(any, error)
+	resp any
+	err  error
+}
@@ -0,0 +1,114 @@
+package actor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func spawnEcho(e *Engine, delay time.Duration) *PID {
+	return e.SpawnFunc(func(c *Context) {
+		if msg, ok := c.Message().(int); ok {
+			time.Sleep(delay)
+			c.Respond(msg)
+		}
+	}, "echo")
+}
+
+func TestWhenAll(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var responses []*Response
+	for i := 0; i < 3; i++ {
+		pid := spawnEcho(e, time.Millisecond*time.Duration(10-i*3))
+		responses = append(responses, e.Request(pid, i, time.Second))
+	}
+	res, err := WhenAll(responses...).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []any{0, 1, 2}, res)
+
+	silent := e.SpawnFunc(func(c *Context) {}, "silent")
+	_, err = WhenAll(
+		e.Request(spawnEcho(e, 0), 1, time.Second),
+		e.Request(silent, 1, time.Millisecond*10),
+	).Result()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWhenAny(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	silent := e.SpawnFunc(func(c *Context) {}, "silent")
+	res, err := WhenAny(
+		e.Request(silent, 1, time.Millisecond*10),
+		e.Request(spawnEcho(e, time.Millisecond*20), 2, time.Second),
+	).Result()
+	require.NoError(t, err)
+	assert.Equal(t, 2, res)
+
+	_, err = WhenAny(
+		e.Request(silent, 1, time.Millisecond*10),
+		e.Request(silent, 2, time.Millisecond*20),
+	).Result()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestResponseMapOnComplete(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	resp := e.Request(spawnEcho(e, 0), 21, time.Second).Map(func(resp any) (any, error) {
+		return resp.(int) * 2, nil
+	})
+	completed := make(chan any, 1)
+	resp.OnComplete(func(resp any, err error) {
+		completed <- resp
+	})
+	assert.Equal(t, 42, <-completed)
+	// callbacks registered after completion are invoked right away.
+	resp.OnComplete(func(resp any, err error) {
+		completed <- resp
+	})
+	assert.Equal(t, 42, <-completed)
+}
+
+func TestResponseCancel(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	silent := e.SpawnFunc(func(c *Context) {}, "silent")
+	resp := e.Request(silent, 1, time.Hour)
+	require.NotNil(t, e.Registry.get(resp.PID()))
+	resp.Cancel()
+	assert.Nil(t, e.Registry.get(resp.PID()))
+	_, err = resp.Result()
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestResponseCancelComposed(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	silent := e.SpawnFunc(func(c *Context) {}, "silent")
+	all := WhenAll(
+		e.Request(silent, 1, time.Hour),
+		WhenAny(e.Request(silent, 2, time.Hour), e.Request(silent, 3, time.Hour)),
+		e.Request(silent, 4, time.Hour).Map(func(resp any) (any, error) { return resp, nil }),
+	)
+	require.Len(t, e.Inspect("response"), 4)
+	all.Cancel()
+	assert.Empty(t, e.Inspect("response"))
+	_, err = all.Result()
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestResponsePIDsAreUnique(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := NewResponse(e, time.Second).PID().ID
+		require.False(t, seen[id])
+		seen[id] = true
+	}
+}
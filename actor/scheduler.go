@@ -0,0 +1,183 @@
+package actor
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+
+	"github.com/anthdm/hollywood/ringbuffer"
+)
+
+// Scheduler runs the inboxes of the actors. An inbox schedules itself
+// each time it receives messages while idle, and processes at most
+// Throughput batches of messages before it yields.
+type Scheduler interface {
+	Schedule(fn func())
+	Throughput() int
+}
+
+type goscheduler int
+
+func (goscheduler) Schedule(fn func()) {
+	go fn()
+}
+
+func (sched goscheduler) Throughput() int {
+	return int(sched)
+}
+
+// NewScheduler returns the default scheduler, which runs each activation of
+// an inbox on a new goroutine.
+func NewScheduler(throughput int) Scheduler {
+	return goscheduler(throughput)
+}
+
+// WorkerPoolScheduler runs the inboxes of all the actors it is given to on
+// a fixed number of goroutines. It acts as a bulkhead: actors sharing the
+// pool can't starve the actors that are scheduled on another one.
+//
+// Note that an actor blocking in Receive blocks one of the workers.
+type WorkerPoolScheduler struct {
+	queue      *ringbuffer.RingBuffer[func()]
+	signal     chan struct{}
+	quit       chan struct{}
+	stopOnce   sync.Once
+	throughput int
+	pinned     bool
+}
+
+// NewWorkerPoolScheduler returns a scheduler backed by the given number of
+// worker goroutines. Use it for a group of actors with WithScheduler.
+func NewWorkerPoolScheduler(workers, throughput int) *WorkerPoolScheduler {
+	return newWorkerPoolScheduler(workers, throughput, false)
+}
+
+// NewPinnedScheduler returns a scheduler with a single dedicated goroutine
+// that is locked to its OS thread, for latency critical actors. It must only
+// be given to a single actor and is stopped when that actor stops.
+func NewPinnedScheduler(throughput int) *WorkerPoolScheduler {
+	return newWorkerPoolScheduler(1, throughput, true)
+}
+
+func newWorkerPoolScheduler(workers, throughput int, pinned bool) *WorkerPoolScheduler {
+	if workers < 1 {
+		workers = 1
+	}
+	s := &WorkerPoolScheduler{
+		queue:      ringbuffer.New[func()](int64(workers * 16)),
+		signal:     make(chan struct{}, workers),
+		quit:       make(chan struct{}),
+		throughput: throughput,
+		pinned:     pinned,
+	}
+	for i := 0; i < workers; i++ {
+		go s.work()
+	}
+	return s
+}
+
+func (s *WorkerPoolScheduler) Schedule(fn func()) {
+	s.queue.Push(fn)
+	// wake up a worker, if they are all awake one of them will pick it up.
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+func (s *WorkerPoolScheduler) Throughput() int {
+	return s.throughput
+}
+
+// Stop stops all the workers once they finish what they are running.
+func (s *WorkerPoolScheduler) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.quit)
+	})
+}
+
+func (s *WorkerPoolScheduler) work() {
+	if s.pinned {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+	}
+	for {
+		for {
+			fn, ok := s.queue.Pop()
+			if !ok {
+				break
+			}
+			fn()
+		}
+		select {
+		case <-s.signal:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// DeterministicScheduler runs the inboxes one at a time on the goroutine
+// that calls Step or RunUntilIdle, instead of on goroutines of their own.
+// The next inbox to run is picked from the scheduled ones by a pseudo-random
+// generator, hence the order messages are delivered in only depends on the
+// seed. Each activation processes a single batch of messages.
+//
+// Actors scheduled on it must not block, and neither must the goroutine that
+// drives it: waiting for the Result of a request or for the Done of a
+// poisoning only returns once the scheduler is run.
+type DeterministicScheduler struct {
+	mu    sync.Mutex
+	rand  *rand.Rand
+	queue []func()
+}
+
+// NewDeterministicScheduler returns a DeterministicScheduler that picks the
+// inboxes in the order given by the seed. Use it for all the actors of an
+// engine with EngineConfig.WithScheduler.
+func NewDeterministicScheduler(seed int64) *DeterministicScheduler {
+	return &DeterministicScheduler{rand: rand.New(rand.NewSource(seed))}
+}
+
+func (s *DeterministicScheduler) Schedule(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, fn)
+}
+
+func (s *DeterministicScheduler) Throughput() int {
+	return 0
+}
+
+// Step runs one of the scheduled inboxes, it returns false when there is
+// none.
+func (s *DeterministicScheduler) Step() bool {
+	s.mu.Lock()
+	if len(s.queue) == 0 {
+		s.mu.Unlock()
+		return false
+	}
+	i := s.rand.Intn(len(s.queue))
+	fn := s.queue[i]
+	s.queue = append(s.queue[:i], s.queue[i+1:]...)
+	s.mu.Unlock()
+	fn()
+	return true
+}
+
+// RunUntilIdle runs the scheduled inboxes, including the ones scheduled
+// while running, until there are none left. It returns the number of steps.
+func (s *DeterministicScheduler) RunUntilIdle() int {
+	n := 0
+	for s.Step() {
+		n++
+	}
+	return n
+}
+
+// Pending returns the number of scheduled inboxes.
+func (s *DeterministicScheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
@@ -0,0 +1,72 @@
+package actor
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkerPoolScheduler(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		pool        = NewWorkerPoolScheduler(2, 1)
+		wg          sync.WaitGroup
+		active, max int32
+	)
+	defer pool.Stop()
+	pids := make([]*PID, 10)
+	for i := range pids {
+		pids[i] = e.SpawnFunc(func(c *Context) {
+			if _, ok := c.Message().(int); ok {
+				n := atomic.AddInt32(&active, 1)
+				for {
+					m := atomic.LoadInt32(&max)
+					if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&active, -1)
+				wg.Done()
+			}
+		}, "worker", WithScheduler(pool))
+	}
+	for i := 0; i < 10; i++ {
+		for _, pid := range pids {
+			wg.Add(1)
+			e.Send(pid, i)
+		}
+	}
+	wg.Wait()
+	require.LessOrEqual(t, atomic.LoadInt32(&max), int32(2))
+}
+
+func TestPinnedScheduler(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		sched    = NewPinnedScheduler(defaultThroughput)
+		received = make(chan int, 100)
+	)
+	pid := e.SpawnFunc(func(c *Context) {
+		if msg, ok := c.Message().(int); ok {
+			received <- msg
+		}
+	}, "pinned", WithScheduler(sched), WithThroughput(1))
+	for i := 0; i < 100; i++ {
+		e.Send(pid, i)
+	}
+	for i := 0; i < 100; i++ {
+		require.Equal(t, i, <-received)
+	}
+	<-e.Poison(pid).Done()
+	select {
+	case <-sched.quit:
+	case <-time.After(time.Second):
+		t.Fatal("expected pinned scheduler to be stopped with its actor")
+	}
+}
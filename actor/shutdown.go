@@ -0,0 +1,99 @@
+package actor
+
+import (
+	"context"
+	"log/slog"
+)
+
+// ShutdownReport is returned by Engine.Shutdown.
+type ShutdownReport struct {
+	// Stopped holds the top-level actors that stopped in time.
+	Stopped []*PID
+	// Unstopped holds all the actors, including children, that were still
+	// running when the context of the shutdown was done.
+	Unstopped []*PID
+}
+
+// Shutdown terminates the whole engine. It stops accepting new spawns,
+// gracefully poisons all the top-level actors, which poison their children
+// first, and waits until they are all stopped or the given context is done.
+// Finally the remote, if any, is stopped, together with all the pending timers.
+//
+// The engine can't be used to spawn actors anymore after Shutdown was called.
+func (e *Engine) Shutdown(ctx context.Context) ShutdownReport {
+	e.stopping.Store(true)
+
+	var (
+		roots  = e.topLevel()
+		report = ShutdownReport{}
+		dones  = make([]context.Context, len(roots))
+	)
+	for i, pid := range roots {
+		dones[i] = e.PoisonCtx(ctx, pid)
+	}
+	// The context of a poison pill is cancelled right after the ActorStoppedEvent
+	// of the process was broadcasted.
+	for _, done := range dones {
+		<-done.Done()
+	}
+	for _, pid := range roots {
+		if e.Registry.get(pid) == nil {
+			report.Stopped = append(report.Stopped, pid)
+		}
+	}
+	report.Unstopped = e.running()
+
+	if e.remote != nil {
+		wg := e.remote.Stop()
+		stopped := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			slog.Warn("shutdown deadline exceeded while stopping the remote", "address", e.address)
+		}
+	}
+	e.timers.stop()
+	return report
+}
+
+// isStopping returns true once the engine is shutting down.
+func (e *Engine) isStopping() bool {
+	return e.stopping.Load()
+}
+
+// topLevel returns the PIDs of all the actors that have no parent.
+func (e *Engine) topLevel() []*PID {
+	pids := []*PID{}
+	e.Registry.each(func(_ string, proc Processer) {
+		p, ok := proc.(*process)
+		if !ok || p.context.parentCtx != nil || e.isSystem(p.pid) {
+			return
+		}
+		pids = append(pids, p.pid)
+	})
+	return pids
+}
+
+// running returns the PIDs of all the actors that are still registered.
+func (e *Engine) running() []*PID {
+	pids := []*PID{}
+	e.Registry.each(func(_ string, proc Processer) {
+		p, ok := proc.(*process)
+		if !ok || e.isSystem(p.pid) {
+			return
+		}
+		pids = append(pids, p.pid)
+	})
+	return pids
+}
+
+// isSystem returns true for the processes of the engine and the remote, which
+// keep running until the engine is gone.
+func (e *Engine) isSystem(pid *PID) bool {
+	_, ok := e.system.Get(pid.ID)
+	return ok
+}
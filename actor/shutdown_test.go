@@ -0,0 +1,77 @@
+package actor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		mu      sync.Mutex
+		stopped []string
+		started = make(chan struct{})
+	)
+	pid := e.SpawnFunc(func(c *Context) {
+		switch c.Message().(type) {
+		case Started:
+			c.SpawnChildFunc(func(c *Context) {
+				switch c.Message().(type) {
+				case Started:
+					close(started)
+				case Stopped:
+					mu.Lock()
+					stopped = append(stopped, "child")
+					mu.Unlock()
+				}
+			}, "child")
+		case Stopped:
+			mu.Lock()
+			stopped = append(stopped, "parent")
+			mu.Unlock()
+		}
+	}, "parent")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	report := e.Shutdown(ctx)
+	require.Len(t, report.Stopped, 1)
+	assert.True(t, report.Stopped[0].Equals(pid))
+	assert.Empty(t, report.Unstopped)
+	assert.Equal(t, []string{"child", "parent"}, stopped)
+
+	assert.Nil(t, e.SpawnFunc(func(c *Context) {}, "foo"))
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		blocking = make(chan struct{})
+		received = make(chan struct{})
+	)
+	defer close(blocking)
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			close(received)
+			<-blocking
+		}
+	}, "blocking")
+	e.SpawnFunc(func(c *Context) {}, "foo")
+	e.Send(pid, "block")
+	<-received
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	report := e.Shutdown(ctx)
+	require.Len(t, report.Stopped, 1)
+	require.Len(t, report.Unstopped, 1)
+	assert.True(t, report.Unstopped[0].Equals(pid))
+}
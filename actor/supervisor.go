@@ -0,0 +1,106 @@
+package actor
+
+// Directive tells the engine what to do with a process that panicked.
+type Directive int
+
+const (
+	// RestartDirective restarts the failing process. This is the default.
+	RestartDirective Directive = iota
+	// ResumeDirective drops the message that caused the failure and keeps
+	// processing the inbox without restarting the process.
+	ResumeDirective
+	// StopDirective stops the failing process.
+	StopDirective
+	// EscalateDirective stops the failing process and lets its parent fail
+	// with the same reason, so the parent's own strategy takes over.
+	EscalateDirective
+)
+
+func (d Directive) String() string {
+	switch d {
+	case RestartDirective:
+		return "restart"
+	case ResumeDirective:
+		return "resume"
+	case StopDirective:
+		return "stop"
+	case EscalateDirective:
+		return "escalate"
+	default:
+		return "unknown"
+	}
+}
+
+// Decider returns the Directive for the given panic value.
+type Decider func(reason any) Directive
+
+// DefaultDecider restarts the process whatever the reason is.
+func DefaultDecider(_ any) Directive {
+	return RestartDirective
+}
+
+// SupervisorStrategy decides how the failure of a process is handled.
+// A parent sets the strategy per child with the WithSupervisor option.
+type SupervisorStrategy interface {
+	// HandleFailure is called on the goroutine of the failing process with
+	// its Context and the recovered panic value. It returns the Directive
+	// for the failing process and may apply it to its siblings as well.
+	HandleFailure(ctx *Context, reason any) Directive
+}
+
+// DefaultSupervisorStrategy is used when no strategy is given on spawn.
+var DefaultSupervisorStrategy = NewOneForOneStrategy(DefaultDecider)
+
+type oneForOneStrategy struct {
+	decider Decider
+}
+
+// NewOneForOneStrategy returns a strategy that only applies the directive
+// of the given Decider to the failing process.
+func NewOneForOneStrategy(decider Decider) SupervisorStrategy {
+	return oneForOneStrategy{decider: decider}
+}
+
+func (s oneForOneStrategy) HandleFailure(_ *Context, reason any) Directive {
+	return s.decider(reason)
+}
+
+type allForOneStrategy struct {
+	decider Decider
+}
+
+// NewAllForOneStrategy returns a strategy that applies the directive of the
+// given Decider to the failing process and all of its siblings. Siblings are
+// restarted or stopped together with the failing process, whatever their own
+// strategy is. Only the failing process counts the restart towards its max
+// restarts and restart delay, its siblings are restarted right away. An
+// escalation only stops the failing process, the parent decides what happens
+// next.
+func NewAllForOneStrategy(decider Decider) SupervisorStrategy {
+	return allForOneStrategy{decider: decider}
+}
+
+func (s allForOneStrategy) HandleFailure(ctx *Context, reason any) Directive {
+	directive := s.decider(reason)
+	for _, pid := range ctx.siblings() {
+		switch directive {
+		case RestartDirective:
+			ctx.engine.SendLocal(pid, restartProcess{reason: reason}, ctx.pid)
+		case StopDirective:
+			ctx.engine.Stop(pid)
+		}
+	}
+	return directive
+}
+
+// restartProcess is sent to the siblings of a failing process when the
+// strategy wants them to restart. It bypasses the strategy of the receiver.
+type restartProcess struct {
+	reason any
+}
+
+// childFailure is sent to the parent when a child escalates its failure.
+type childFailure struct {
+	child  *PID
+	reason any
+}
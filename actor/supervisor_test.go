@@ -0,0 +1,187 @@
+package actor
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupervisorResume(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		wg      = sync.WaitGroup{}
+		started int32
+		count   int
+	)
+	wg.Add(1)
+	pid := e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case Started:
+			atomic.AddInt32(&started, 1)
+		case int:
+			if msg == 0 {
+				panic("resume me")
+			}
+			count += msg
+			if count == 3 {
+				wg.Done()
+			}
+		}
+	}, "foo", WithSupervisor(NewOneForOneStrategy(func(any) Directive {
+		return ResumeDirective
+	})))
+	e.Send(pid, 1)
+	e.Send(pid, 0)
+	e.Send(pid, 2)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&started))
+}
+
+func TestSupervisorStop(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	stoppedch := make(chan struct{})
+	pid := e.SpawnFunc(func(c *Context) {
+		switch c.Message().(type) {
+		case Stopped:
+			close(stoppedch)
+		case string:
+			panic("stop me")
+		}
+	}, "foo", WithSupervisor(NewOneForOneStrategy(func(any) Directive {
+		return StopDirective
+	})))
+	e.Send(pid, "boom")
+	select {
+	case <-stoppedch:
+	case <-time.After(time.Second):
+		t.Fatal("actor was not stopped")
+	}
+	assert.Eventually(t, func() bool {
+		return e.Registry.get(pid) == nil
+	}, time.Second, time.Millisecond)
+}
+
+func TestSupervisorAllForOne(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		wg       = sync.WaitGroup{}
+		starts   = make(map[string]int)
+		mu       sync.Mutex
+		strategy = NewAllForOneStrategy(DefaultDecider)
+	)
+	wg.Add(3)
+	childfn := func(c *Context) {
+		switch c.Message().(type) {
+		case Started:
+			mu.Lock()
+			starts[c.PID().ID]++
+			if starts[c.PID().ID] == 2 {
+				wg.Done()
+			}
+			mu.Unlock()
+		case string:
+			panic("restart all")
+		}
+	}
+	e.SpawnFunc(func(c *Context) {
+		switch c.Message().(type) {
+		case Started:
+			a := c.SpawnChildFunc(childfn, "child", WithID("a"), WithSupervisor(strategy), WithRestartDelay(0))
+			c.SpawnChildFunc(childfn, "child", WithID("b"), WithSupervisor(strategy), WithRestartDelay(0))
+			c.SpawnChildFunc(childfn, "child", WithID("c"), WithSupervisor(strategy), WithRestartDelay(0))
+			c.Send(a, "boom")
+		}
+	}, "parent")
+	wg.Wait()
+}
+
+func TestSupervisorAllForOneKeepsSiblingRestarts(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		started  = make(chan string, 100)
+		children = make(chan []*PID, 1)
+		strategy = NewAllForOneStrategy(DefaultDecider)
+	)
+	childfn := func(c *Context) {
+		switch c.Message().(type) {
+		case Started:
+			started <- c.PID().ID
+		case string:
+			panic("restart all")
+		}
+	}
+	e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(Started); ok {
+			var pids []*PID
+			for _, id := range []string{"a", "b", "c"} {
+				pids = append(pids, c.SpawnChildFunc(childfn, "child", WithID(id),
+					WithSupervisor(strategy), WithRestartDelay(0), WithMaxRestarts(1)))
+			}
+			children <- pids
+		}
+	}, "parent")
+	pids := <-children
+	waitStarts := func(n int) {
+		for i := 0; i < n; i++ {
+			select {
+			case <-started:
+			case <-time.After(time.Second):
+				t.Fatal("children were not restarted")
+			}
+		}
+	}
+	waitStarts(3)
+	restarts := func() []int32 {
+		var counts []int32
+		for _, pid := range pids {
+			info := e.Inspect(pid.ID)
+			require.Len(t, info, 1)
+			counts = append(counts, info[0].Restarts)
+		}
+		return counts
+	}
+
+	e.Send(pids[0], "boom")
+	waitStarts(3)
+	assert.Equal(t, []int32{1, 0, 0}, restarts())
+	// b still has its own restart to spend.
+	e.Send(pids[1], "boom")
+	waitStarts(3)
+	assert.Equal(t, []int32{1, 1, 0}, restarts())
+}
+
+func TestSupervisorEscalate(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		wg     = sync.WaitGroup{}
+		starts int32
+	)
+	wg.Add(1)
+	e.SpawnFunc(func(c *Context) {
+		switch c.Message().(type) {
+		case Started:
+			if atomic.AddInt32(&starts, 1) == 2 {
+				wg.Done()
+				return
+			}
+			child := c.SpawnChildFunc(func(c *Context) {
+				if _, ok := c.Message().(string); ok {
+					panic("escalate")
+				}
+			}, "child", WithSupervisor(NewOneForOneStrategy(func(any) Directive {
+				return EscalateDirective
+			})))
+			c.Send(child, "boom")
+		}
+	}, "parent", WithRestartDelay(0))
+	wg.Wait()
+}
@@ -0,0 +1,354 @@
+package actor
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// The timer wheel has a first level of 256 slots of one tick each and four
+// more levels of 64 slots, each slot spanning a full turn of the level
+// below. Timers are cascaded down a level each time the level below
+// completed a turn, hence adding and stopping a timer is O(1). Timers that
+// expire beyond the span of the wheel, about 49.7 days, are parked in its
+// last slot and added again from there until they fit.
+const (
+	wheelTick       = time.Millisecond
+	wheelRootBits   = 8
+	wheelLevelBits  = 6
+	wheelRootSize   = 1 << wheelRootBits
+	wheelLevelSize  = 1 << wheelLevelBits
+	wheelLevels     = 4
+	wheelMaxTimeout = 1<<(wheelRootBits+wheelLevels*wheelLevelBits) - 1
+)
+
+// Timer is a handle to a message scheduled by SendAfter or SendRepeat.
+type Timer struct {
+	wheel    *timerWheel
+	fn       func()
+	expires  uint64
+	interval uint64
+	// slot is the list the timer is linked into, nil when not scheduled.
+	slot       *timerList
+	prev, next *Timer
+	// onStop is called once the timer will never fire again.
+	onStop func(*Timer)
+}
+
+// Stop cancels the timer. It returns false if the timer already fired or
+// was stopped before.
+func (t *Timer) Stop() bool {
+	if t == nil || t.wheel == nil {
+		return false
+	}
+	return t.wheel.remove(t)
+}
+
+type timerList struct {
+	head *Timer
+}
+
+func (l *timerList) push(t *Timer) {
+	t.slot = l
+	t.prev = nil
+	t.next = l.head
+	if l.head != nil {
+		l.head.prev = t
+	}
+	l.head = t
+}
+
+func (l *timerList) unlink(t *Timer) {
+	if t.prev != nil {
+		t.prev.next = t.next
+	} else {
+		l.head = t.next
+	}
+	if t.next != nil {
+		t.next.prev = t.prev
+	}
+	t.slot, t.prev, t.next = nil, nil, nil
+}
+
+// take removes all the timers from the list and returns them.
+func (l *timerList) take() []*Timer {
+	var timers []*Timer
+	for t := l.head; t != nil; {
+		next := t.next
+		t.slot, t.prev, t.next = nil, nil, nil
+		timers = append(timers, t)
+		t = next
+	}
+	l.head = nil
+	return timers
+}
+
+// timerWheel runs all the timers of an engine on a single goroutine, which
+// only runs while there are timers scheduled and sleeps until the next tick
+// at which a timer expires. With a clock other than the system clock, the
+// wheel is advanced by a timer of the clock set to that tick instead.
+type timerWheel struct {
+	clock   Clock
+	mu      sync.Mutex
+	root    [wheelRootSize]timerList
+	levels  [wheelLevels][wheelLevelSize]timerList
+	now     uint64
+	base    time.Time
+	pending int
+	running bool
+	stopped bool
+	// wakeAt is the tick the goroutine of the wheel sleeps until, wake
+	// interrupts its sleep when a timer expiring earlier is started.
+	wakeAt uint64
+	wake   chan struct{}
+	// clockTimer advances the wheel with a clock other than the system clock.
+	clockTimer ClockTimer
+}
+
+func newTimerWheel(clock Clock) *timerWheel {
+	return &timerWheel{
+		clock: clock,
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// newTimer returns a timer that runs fn, each interval if interval is not
+// zero. The function must not block since it runs on the goroutine of the
+// wheel. onStop, if not nil, is called once the timer will never fire again.
+func (w *timerWheel) newTimer(fn func(), interval time.Duration, onStop func(*Timer)) *Timer {
+	return &Timer{
+		wheel:    w,
+		fn:       fn,
+		interval: ticks(interval),
+		onStop:   onStop,
+	}
+}
+
+// start schedules the timer to fire after d.
+func (w *timerWheel) start(t *Timer, d time.Duration) *Timer {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return t
+	}
+	if !w.running {
+		// re-anchor the current tick, the wheel didn't advance while idle.
+		w.base = w.clock.Now().Add(-time.Duration(w.now) * wheelTick)
+		w.running = true
+		w.wakeAt = math.MaxUint64
+		if isSystemClock(w.clock) {
+			go w.run()
+		}
+	}
+	t.expires = w.now + ticks(d)
+	w.add(t)
+	w.pending++
+	if t.expires < w.wakeAt {
+		w.wakeAt = t.expires
+		w.wakeUp()
+	}
+	return t
+}
+
+func (w *timerWheel) remove(t *Timer) bool {
+	w.mu.Lock()
+	if t.slot == nil {
+		w.mu.Unlock()
+		return false
+	}
+	t.slot.unlink(t)
+	w.pending--
+	onStop := t.onStop
+	w.mu.Unlock()
+	if onStop != nil {
+		onStop(t)
+	}
+	return true
+}
+
+// stop cancels all the timers and stops the goroutine of the wheel.
+func (w *timerWheel) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	for i := range w.root {
+		w.root[i].take()
+	}
+	for i := range w.levels {
+		for j := range w.levels[i] {
+			w.levels[i][j].take()
+		}
+	}
+	w.pending = 0
+	if w.clockTimer != nil {
+		w.clockTimer.Stop()
+	}
+}
+
+// add links the timer into the slot of its expiry, w.mu must be held.
+func (w *timerWheel) add(t *Timer) {
+	delta := t.expires - w.now
+	if t.expires < w.now {
+		delta = 0
+		t.expires = w.now
+	}
+	if delta < wheelRootSize {
+		w.root[t.expires&(wheelRootSize-1)].push(t)
+		return
+	}
+	at := t.expires
+	if delta > wheelMaxTimeout {
+		at = w.now + wheelMaxTimeout
+	}
+	for level := 0; level < wheelLevels; level++ {
+		shift := wheelRootBits + level*wheelLevelBits
+		if delta < 1<<(shift+wheelLevelBits) || level == wheelLevels-1 {
+			w.levels[level][(at>>shift)&(wheelLevelSize-1)].push(t)
+			return
+		}
+	}
+}
+
+func (w *timerWheel) run() {
+	timer := time.NewTimer(wheelTick)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-w.wake:
+		}
+		d, ok := w.advance(time.Now())
+		if !ok {
+			return
+		}
+		timer.Reset(d)
+	}
+}
+
+// step advances the wheel to the time of the clock and schedules the next
+// step while there are timers left.
+func (w *timerWheel) step() {
+	if _, ok := w.advance(w.clock.Now()); ok {
+		w.mu.Lock()
+		w.wakeUp()
+		w.mu.Unlock()
+	}
+}
+
+// wakeUp makes the wheel process the tick w.wakeAt, w.mu must be held.
+func (w *timerWheel) wakeUp() {
+	if isSystemClock(w.clock) {
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+		return
+	}
+	d := w.base.Add(time.Duration(w.wakeAt) * wheelTick).Sub(w.clock.Now())
+	if w.clockTimer == nil {
+		w.clockTimer = w.clock.AfterFunc(d, w.step)
+		return
+	}
+	w.clockTimer.Reset(d)
+}
+
+// advance processes all the ticks up to the given time, skipping the ticks
+// at which nothing happens. It returns how long to wait until the next tick
+// that needs processing, and false when there are no timers left, which
+// stops the goroutine of the wheel.
+func (w *timerWheel) advance(now time.Time) (time.Duration, bool) {
+	target := uint64(now.Sub(w.base) / wheelTick)
+	for {
+		w.mu.Lock()
+		if w.stopped || w.pending == 0 {
+			w.running = false
+			w.mu.Unlock()
+			return 0, false
+		}
+		next := w.next()
+		if next > target {
+			if w.now <= target {
+				w.now = target + 1
+			}
+			w.wakeAt = next
+			d := w.base.Add(time.Duration(next) * wheelTick).Sub(now)
+			w.mu.Unlock()
+			return d, true
+		}
+		w.now = next
+		expired := w.tick()
+		w.mu.Unlock()
+		for _, t := range expired {
+			t.fn()
+			if t.interval == 0 && t.onStop != nil {
+				t.onStop(t)
+			}
+		}
+	}
+}
+
+// next returns the first tick from the current one on at which a timer
+// expires or a level with timers is cascaded. w.mu must be held.
+func (w *timerWheel) next() uint64 {
+	next := uint64(math.MaxUint64)
+	for i := uint64(0); i < wheelRootSize; i++ {
+		if w.root[(w.now+i)&(wheelRootSize-1)].head != nil {
+			next = w.now + i
+			break
+		}
+	}
+	// a slot of a level is cascaded when the current tick is a multiple of
+	// the span of the slots of that level and points to it.
+	for level := 0; level < wheelLevels; level++ {
+		shift := wheelRootBits + level*wheelLevelBits
+		first := (w.now + 1<<shift - 1) >> shift
+		for i := uint64(0); i < wheelLevelSize; i++ {
+			at := (first + i) << shift
+			if at >= next {
+				break
+			}
+			if w.levels[level][(first+i)&(wheelLevelSize-1)].head != nil {
+				next = at
+				break
+			}
+		}
+	}
+	return next
+}
+
+// tick cascades the levels that completed a turn, returns the timers that
+// expire at the current tick and moves to the next one. w.mu must be held.
+func (w *timerWheel) tick() []*Timer {
+	index := w.now & (wheelRootSize - 1)
+	if index == 0 {
+		for level := 0; level < wheelLevels; level++ {
+			shift := wheelRootBits + level*wheelLevelBits
+			slot := (w.now >> shift) & (wheelLevelSize - 1)
+			for _, t := range w.levels[level][slot].take() {
+				w.add(t)
+			}
+			if slot != 0 {
+				break
+			}
+		}
+	}
+	expired := w.root[index].take()
+	w.now++
+	for _, t := range expired {
+		if t.interval > 0 {
+			t.expires = w.now - 1 + t.interval
+			w.add(t)
+		} else {
+			w.pending--
+		}
+	}
+	return expired
+}
+
+// ticks converts the duration to wheel ticks, rounding up.
+func ticks(d time.Duration) uint64 {
+	if d <= 0 {
+		return 0
+	}
+	return uint64((d + wheelTick - 1) / wheelTick)
+}
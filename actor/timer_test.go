@@ -0,0 +1,217 @@
+package actor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newManualWheel returns a wheel that is only advanced by the test.
+func newManualWheel() *timerWheel {
+	w := newTimerWheel(SystemClock())
+	w.running = true
+	w.base = time.Unix(0, 0)
+	return w
+}
+
+func TestTimerWheelExpiry(t *testing.T) {
+	w := newManualWheel()
+	// start right before the higher levels complete a turn.
+	const offset = 1<<20 - 5000
+	w.now = offset
+	fired := make(map[uint64]uint64)
+	for _, n := range []uint64{0, 1, 255, 256, 257, 300, 5000, 16383, 16384, 20000, 40000} {
+		n := n
+		w.start(w.newTimer(func() {
+			fired[n] = w.now - 1 - offset
+		}, 0, nil), time.Duration(n)*wheelTick)
+	}
+	w.advance(w.base.Add(time.Duration(offset+40001) * wheelTick))
+	require.Len(t, fired, 11)
+	for n, at := range fired {
+		assert.Equal(t, n, at, "timer of %d ticks fired at %d", n, at)
+	}
+	assert.Equal(t, 0, w.pending)
+}
+
+func TestTimerWheelRepeat(t *testing.T) {
+	w := newManualWheel()
+	var fired []uint64
+	timer := w.start(w.newTimer(func() {
+		fired = append(fired, w.now-1)
+	}, time.Duration(300)*wheelTick, nil), time.Duration(300)*wheelTick)
+	w.advance(w.base.Add(time.Duration(1000) * wheelTick))
+	assert.Equal(t, []uint64{300, 600, 900}, fired)
+	assert.True(t, timer.Stop())
+	assert.False(t, timer.Stop())
+	assert.Equal(t, 0, w.pending)
+}
+
+func TestTimerWheelSkipsIdleTicks(t *testing.T) {
+	w := newManualWheel()
+	var fired uint64
+	w.start(w.newTimer(func() {
+		fired = w.now - 1
+	}, 0, nil), time.Hour)
+	now := w.base
+	wakeups := 0
+	for {
+		d, ok := w.advance(now)
+		if !ok {
+			break
+		}
+		wakeups++
+		require.Greater(t, d, time.Duration(0))
+		now = now.Add(d)
+	}
+	assert.Equal(t, uint64(time.Hour/wheelTick), fired)
+	assert.Less(t, wakeups, 10)
+}
+
+// countingClock counts how often the timers of the clock fired.
+type countingClock struct {
+	*VirtualClock
+	fired int
+}
+
+func (c *countingClock) AfterFunc(d time.Duration, fn func()) ClockTimer {
+	return c.VirtualClock.AfterFunc(d, func() {
+		c.fired++
+		fn()
+	})
+}
+
+func TestTimerWheelVirtualClockSkipsIdleTicks(t *testing.T) {
+	clock := &countingClock{VirtualClock: NewVirtualClock(time.Unix(0, 0))}
+	w := newTimerWheel(clock)
+	var fired []time.Duration
+	w.start(w.newTimer(func() {
+		fired = append(fired, clock.Now().Sub(time.Unix(0, 0)))
+	}, 0, nil), 20*time.Hour)
+	clock.Advance(24 * time.Hour)
+	assert.Equal(t, []time.Duration{20 * time.Hour}, fired)
+	assert.Less(t, clock.fired, 10)
+	assert.Equal(t, 0, clock.Pending())
+}
+
+func TestTimerWheelStop(t *testing.T) {
+	w := newManualWheel()
+	var stopped int
+	timer := w.start(w.newTimer(func() {
+		t.Fatal("stopped timer fired")
+	}, 0, func(*Timer) { stopped++ }), time.Duration(500)*wheelTick)
+	assert.True(t, timer.Stop())
+	w.advance(w.base.Add(time.Duration(1000) * wheelTick))
+	assert.Equal(t, 1, stopped)
+}
+
+func TestEngineSendAfter(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	received := make(chan time.Time, 2)
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			received <- time.Now()
+		}
+	}, "foo")
+	start := time.Now()
+	e.SendAfter(pid, "delayed", time.Millisecond*20)
+	e.SendAfter(pid, "cancelled", time.Millisecond*10).Stop()
+	select {
+	case at := <-received:
+		assert.GreaterOrEqual(t, at.Sub(start), time.Millisecond*20)
+	case <-time.After(time.Second):
+		t.Fatal("delayed message was not received")
+	}
+	select {
+	case <-received:
+		t.Fatal("cancelled message was received")
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestContextTimersStopWithProcess(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		ticks   = make(chan struct{}, 100)
+		started = make(chan *Context, 1)
+	)
+	target := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			ticks <- struct{}{}
+		}
+	}, "target")
+	pid := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(Started); ok {
+			c.SendRepeat(target, "tick", time.Millisecond*5)
+			c.SendAfter(target, "later", time.Hour)
+			started <- c
+		}
+	}, "owner")
+	ctx := <-started
+	<-ticks
+	<-e.Poison(pid).Done()
+
+	ctx.timersMu.Lock()
+	assert.Empty(t, ctx.timers)
+	ctx.timersMu.Unlock()
+	// drain the ticks that were sent while stopping.
+	time.Sleep(time.Millisecond * 10)
+	for len(ticks) > 0 {
+		<-ticks
+	}
+	select {
+	case <-ticks:
+		t.Fatal("repeater was not stopped with its process")
+	case <-time.After(time.Millisecond * 30):
+	}
+}
+
+func TestTimerWheelBeyondSpan(t *testing.T) {
+	w := newManualWheel()
+	const d = 60 * 24 * time.Hour
+	var fired uint64
+	w.start(w.newTimer(func() {
+		fired = w.now - 1
+	}, 0, nil), d)
+	now := w.base
+	for {
+		d, ok := w.advance(now)
+		if !ok {
+			break
+		}
+		now = now.Add(d)
+	}
+	assert.Equal(t, uint64(d/wheelTick), fired)
+}
+
+func TestTimersDontWaitForBlockedInbox(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	release := make(chan struct{})
+	defer close(release)
+	blocked := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			<-release
+		}
+	}, "blocked", WithInboxSize(1), WithOverflowPolicy(OverflowBlock), WithBlockTimeout(time.Second*5))
+	received := make(chan struct{}, 1)
+	other := e.SpawnFunc(func(c *Context) {
+		if _, ok := c.Message().(string); ok {
+			received <- struct{}{}
+		}
+	}, "other")
+	// the first message is received and blocks, the second fills the inbox.
+	e.Send(blocked, "a")
+	e.Send(blocked, "b")
+	e.SendAfter(blocked, "c", time.Millisecond)
+	e.SendAfter(other, "d", time.Millisecond*20)
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("timer was held up by a blocked inbox")
+	}
+}
@@ -0,0 +1,38 @@
+package actor
+
+import "encoding/hex"
+
+// TraceID identifies a trace, all the spans of a trace share its TraceID.
+type TraceID [16]byte
+
+// IsValid reports whether the TraceID is not all zero.
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanID identifies a span inside a trace.
+type SpanID [8]byte
+
+// IsValid reports whether the SpanID is not all zero.
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanContext is the trace context that is carried by an Envelope, locally
+// and over the network. The zero value is an empty SpanContext.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+// IsValid reports whether both the TraceID and the SpanID are valid.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// EnvelopeSender is implemented by the Remoters that send the whole Envelope
+// over the network, including its SpanContext. Remoters that do not implement
+// it only send the message and its sender.
+type EnvelopeSender interface {
+	SendEnvelope(pid *PID, env Envelope)
+}
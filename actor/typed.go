@@ -0,0 +1,75 @@
+package actor
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// ErrResponseType is returned by Ask when the response is not of the
+// expected type.
+var ErrResponseType = errors.New("unexpected response type")
+
+// TypedPID is the PID of a process that receives messages of type M.
+type TypedPID[M any] struct {
+	pid *PID
+}
+
+// NewTypedPID returns a TypedPID for the given PID. It's up to the caller
+// to make sure the process receives messages of type M.
+func NewTypedPID[M any](pid *PID) TypedPID[M] {
+	return TypedPID[M]{pid: pid}
+}
+
+// PID returns the underlying PID.
+func (p TypedPID[M]) PID() *PID {
+	return p.pid
+}
+
+func (p TypedPID[M]) String() string {
+	return p.pid.String()
+}
+
+// SpawnTyped spawns the given function as a stateless receiver of messages of
+// type M. The lifecycle messages are not passed to the function, messages of
+// any other type are dropped as a DeadLetterEvent.
+func SpawnTyped[M any](e *Engine, f func(*Context, M), kind string, opts ...OptFunc) TypedPID[M] {
+	pid := e.SpawnFunc(func(c *Context) {
+		switch msg := c.Message().(type) {
+		case Initialized, Started, Stopped:
+		case M:
+			f(c, msg)
+		default:
+			c.engine.BroadcastEvent(DeadLetterEvent{
+				Target:  c.pid,
+				Message: msg,
+				Sender:  c.sender,
+				Headers: c.headers,
+				Trace:   c.span,
+			})
+		}
+	}, kind, opts...)
+	return NewTypedPID[M](pid)
+}
+
+// SendTyped sends the given message to the given TypedPID.
+func SendTyped[M any](e *Engine, pid TypedPID[M], msg M) {
+	e.Send(pid.pid, msg)
+}
+
+// Ask sends the given request to the given TypedPID and waits for a response
+// of type Resp. It returns an error wrapping ErrResponseType if the response
+// has another type.
+func Ask[Req, Resp any](e *Engine, pid TypedPID[Req], req Req, timeout time.Duration) (Resp, error) {
+	var zero Resp
+	resp, err := e.Request(pid.pid, req, timeout).Result()
+	if err != nil {
+		return zero, err
+	}
+	typed, ok := resp.(Resp)
+	if !ok {
+		return zero, fmt.Errorf("%w: expected %s, got %T", ErrResponseType, reflect.TypeOf((*Resp)(nil)).Elem(), resp)
+	}
+	return typed, nil
+}
@@ -0,0 +1,79 @@
+package actor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type addRequest struct {
+	a, b int
+}
+
+func TestSpawnTyped(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	received := make(chan int, 1)
+	pid := SpawnTyped(e, func(c *Context, msg addRequest) {
+		received <- msg.a + msg.b
+	}, "adder")
+	SendTyped(e, pid, addRequest{a: 1, b: 2})
+	assert.Equal(t, 3, <-received)
+}
+
+func TestSpawnTypedSkipsLifecycle(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	received := make(chan any, 4)
+	pid := SpawnTyped(e, func(c *Context, msg any) {
+		received <- msg
+	}, "any")
+	SendTyped[any](e, pid, "foo")
+	assert.Equal(t, "foo", <-received)
+	<-e.Poison(pid.PID()).Done()
+	select {
+	case msg := <-received:
+		t.Fatalf("unexpected message %v", msg)
+	default:
+	}
+}
+
+func TestSpawnTypedDeadLetter(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	deadletters := make(chan DeadLetterEvent, 1)
+	sub := e.SpawnFunc(func(c *Context) {
+		if msg, ok := c.Message().(DeadLetterEvent); ok {
+			deadletters <- msg
+		}
+	}, "sub")
+	e.Subscribe(sub)
+	pid := SpawnTyped(e, func(c *Context, msg addRequest) {}, "adder")
+	time.Sleep(time.Millisecond * 10)
+	e.Send(pid.PID(), "not an addRequest")
+	select {
+	case msg := <-deadletters:
+		assert.Equal(t, "not an addRequest", msg.Message)
+		assert.True(t, msg.Target.Equals(pid.PID()))
+	case <-time.After(time.Second):
+		t.Fatal("expected a DeadLetterEvent")
+	}
+}
+
+func TestAsk(t *testing.T) {
+	e, err := NewEngine(NewEngineConfig())
+	require.NoError(t, err)
+	pid := SpawnTyped(e, func(c *Context, msg addRequest) {
+		c.Respond(msg.a + msg.b)
+	}, "adder")
+
+	sum, err := Ask[addRequest, int](e, pid, addRequest{a: 1, b: 2}, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 3, sum)
+
+	s, err := Ask[addRequest, string](e, pid, addRequest{a: 1, b: 2}, time.Second)
+	assert.ErrorIs(t, err, ErrResponseType)
+	assert.Empty(t, s)
+}
@@ -14,3 +14,13 @@ type poisonPill struct {
 type Initialized struct{}
 type Started struct{}
 type Stopped struct{}
+
+// ReceiveTimeout is received when the actor did not receive any message
+// for the duration set with WithReceiveTimeout or Context.SetReceiveTimeout.
+type ReceiveTimeout struct{}
+
+// PipeToError is received instead of the result of the function given to
+// Context.PipeTo when it panicked, Reason is the recovered value.
+type PipeToError struct {
+	Reason any
+}
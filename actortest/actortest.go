@@ -0,0 +1,277 @@
+// Package actortest provides utilities to test actors without channels,
+// wait groups and sleeps. A TestProbe is an actor that records the messages
+// it receives, which the test asserts in order with ExpectMsg, ExpectNoMsg,
+// FishForMessage and ExpectTerminated:
+//
+//	func TestGreeter(t *testing.T) {
+//		e, _ := actor.NewEngine(actor.NewEngineConfig())
+//		pid, probe := actortest.Spawn(t, e, newGreeter, "greeter")
+//		probe.Send(pid, Greet{Name: "bob"})
+//		greeting := actortest.ExpectMsg[Greeting](probe, time.Second)
+//		assert.Equal(t, "hello bob", greeting.Text)
+//	}
+package actortest
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthdm/hollywood/actor"
+)
+
+// Message is a message recorded by a TestProbe.
+type Message struct {
+	Msg    any
+	Sender *actor.PID
+	// Target is the PID the message was sent to. It is the PID of the probe,
+	// unless the message was intercepted on its way to another actor.
+	Target  *actor.PID
+	Headers map[string]string
+}
+
+// watchRequest asks the probe to watch the given PID.
+type watchRequest struct {
+	pid *actor.PID
+}
+
+// TestProbe is an actor that records every message it receives, except for
+// the lifecycle messages. Messages are expected in the order they were
+// received, each expected message is consumed.
+type TestProbe struct {
+	t      testing.TB
+	engine *actor.Engine
+	pid    *actor.PID
+
+	mu       sync.Mutex
+	queue    []Message
+	received []Message
+	last     Message
+	notify   chan struct{}
+}
+
+// NewTestProbe spawns a new TestProbe in the given engine, which is poisoned
+// when the test finishes.
+func NewTestProbe(t testing.TB, e *actor.Engine) *TestProbe {
+	p := &TestProbe{
+		t:      t,
+		engine: e,
+		notify: make(chan struct{}, 1),
+	}
+	p.pid = e.SpawnFunc(p.receive, "probe")
+	t.Cleanup(func() {
+		e.Poison(p.pid)
+	})
+	return p
+}
+
+func (p *TestProbe) receive(c *actor.Context) {
+	switch msg := c.Message().(type) {
+	case actor.Initialized, actor.Started, actor.Stopped:
+	case watchRequest:
+		c.Watch(msg.pid)
+	default:
+		p.record(Message{Msg: msg, Sender: c.Sender(), Target: c.PID(), Headers: c.Headers()})
+	}
+}
+
+func (p *TestProbe) record(msg Message) {
+	p.mu.Lock()
+	p.queue = append(p.queue, msg)
+	p.received = append(p.received, msg)
+	p.mu.Unlock()
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// PID returns the PID of the probe.
+func (p *TestProbe) PID() *actor.PID {
+	return p.pid
+}
+
+// Send sends the given message to the given PID with the probe as sender,
+// hence the responses of the receiver are recorded by the probe.
+func (p *TestProbe) Send(pid *actor.PID, msg any) {
+	p.engine.SendWithSender(pid, msg, p.pid)
+}
+
+// Respond sends the given message to the sender of the last expected
+// message, with the probe as sender.
+func (p *TestProbe) Respond(msg any) {
+	p.t.Helper()
+	sender := p.Last().Sender
+	if sender == nil {
+		p.t.Fatalf("actortest: the last expected message %T has no sender", p.Last().Msg)
+	}
+	p.Send(sender, msg)
+}
+
+// Last returns the last expected message.
+func (p *TestProbe) Last() Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.last
+}
+
+// Sender returns the sender of the last expected message.
+func (p *TestProbe) Sender() *actor.PID {
+	return p.Last().Sender
+}
+
+// Received returns all the messages recorded by the probe, including the
+// ones that were already expected, in the order they were received.
+func (p *TestProbe) Received() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Message(nil), p.received...)
+}
+
+// Expect returns the next message, it fails the test when no message is
+// received within the given timeout.
+func (p *TestProbe) Expect(timeout time.Duration) Message {
+	p.t.Helper()
+	msg, ok := p.next(timeout)
+	if !ok {
+		p.t.Fatalf("actortest: timeout (%v) waiting for a message", timeout)
+	}
+	return msg
+}
+
+// ExpectNoMsg fails the test when a message is received within the given
+// duration.
+func (p *TestProbe) ExpectNoMsg(d time.Duration) {
+	p.t.Helper()
+	if msg, ok := p.next(d); ok {
+		p.t.Fatalf("actortest: expected no message, received %T: %+v", msg.Msg, msg.Msg)
+	}
+}
+
+// FishForMessage returns the first message for which pred returns true, the
+// messages received before are dropped. It fails the test when no such
+// message is received within the given timeout.
+func (p *TestProbe) FishForMessage(pred func(msg any) bool, timeout time.Duration) any {
+	p.t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		msg, ok := p.next(time.Until(deadline))
+		if !ok {
+			p.t.Fatalf("actortest: timeout (%v) fishing for a message", timeout)
+		}
+		if pred(msg.Msg) {
+			return msg.Msg
+		}
+	}
+}
+
+// ExpectTerminated watches the given PID and waits until it terminates, the
+// messages received before are dropped. It fails the test when the process
+// does not terminate within the given timeout.
+func (p *TestProbe) ExpectTerminated(pid *actor.PID, timeout time.Duration) actor.Terminated {
+	p.t.Helper()
+	p.engine.Send(p.pid, watchRequest{pid: pid})
+	msg := p.FishForMessage(func(msg any) bool {
+		terminated, ok := msg.(actor.Terminated)
+		return ok && terminated.PID.Equals(pid)
+	}, timeout)
+	return msg.(actor.Terminated)
+}
+
+// next consumes the next message, it returns false when no message is
+// received within the given timeout.
+func (p *TestProbe) next(timeout time.Duration) (Message, bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		p.mu.Lock()
+		if len(p.queue) > 0 {
+			msg := p.queue[0]
+			p.queue = p.queue[1:]
+			p.last = msg
+			p.mu.Unlock()
+			return msg, true
+		}
+		p.mu.Unlock()
+		select {
+		case <-p.notify:
+		case <-timer.C:
+			return Message{}, false
+		}
+	}
+}
+
+// ExpectMsg returns the next message of the probe, it fails the test when it
+// is not of type T or when no message is received within the given timeout.
+func ExpectMsg[T any](p *TestProbe, timeout time.Duration) T {
+	p.t.Helper()
+	msg := p.Expect(timeout)
+	v, ok := msg.Msg.(T)
+	if !ok {
+		p.t.Fatalf("actortest: expected message of type %v, received %T: %+v",
+			reflect.TypeOf((*T)(nil)).Elem(), msg.Msg, msg.Msg)
+	}
+	return v
+}
+
+// Intercept returns the send middleware that records the messages sent by an
+// actor in the given probe instead of delivering them, see
+// actor.WithSendMiddleware. The messages are recorded on the goroutine of the
+// actor, before the send returns. Messages to the targets for which deliver
+// returns true are delivered as usual, deliver may be nil.
+func Intercept(p *TestProbe, deliver func(target *actor.PID) bool) actor.SendMiddlewareFunc {
+	return func(next actor.SendFunc) actor.SendFunc {
+		return func(pid *actor.PID, env actor.Envelope) {
+			if deliver != nil && deliver(pid) {
+				next(pid, env)
+				return
+			}
+			p.record(Message{Msg: env.Msg, Sender: env.Sender, Target: pid, Headers: env.Headers})
+		}
+	}
+}
+
+// Spawn spawns the actor under test with its sends intercepted by a new
+// TestProbe. The messages the actor sends to itself and to its children are
+// delivered. The actor is poisoned when the test finishes.
+func Spawn(t testing.TB, e *actor.Engine, p actor.Producer, kind string, opts ...actor.OptFunc) (*actor.PID, *TestProbe) {
+	probe := NewTestProbe(t, e)
+	pid := e.Spawn(p, kind, append(opts, interceptOpts(probe)...)...)
+	t.Cleanup(func() {
+		e.Poison(pid)
+	})
+	return pid, probe
+}
+
+// SpawnFunc behaves like Spawn for the given function.
+func SpawnFunc(t testing.TB, e *actor.Engine, f func(*actor.Context), kind string, opts ...actor.OptFunc) (*actor.PID, *TestProbe) {
+	probe := NewTestProbe(t, e)
+	pid := e.SpawnFunc(f, kind, append(opts, interceptOpts(probe)...)...)
+	t.Cleanup(func() {
+		e.Poison(pid)
+	})
+	return pid, probe
+}
+
+// interceptOpts returns the options that intercept the sends of an actor in
+// the probe, except for the ones to the actor itself and to its children. The
+// PID of the actor is taken from its context before it receives anything.
+func interceptOpts(probe *TestProbe) []actor.OptFunc {
+	var self atomic.Pointer[actor.PID]
+	return []actor.OptFunc{
+		actor.WithMiddleware(func(next actor.ReceiveFunc) actor.ReceiveFunc {
+			return func(c *actor.Context) {
+				self.CompareAndSwap(nil, c.PID())
+				next(c)
+			}
+		}),
+		actor.WithSendMiddleware(Intercept(probe, func(target *actor.PID) bool {
+			pid := self.Load()
+			return pid != nil && target.Address == pid.Address &&
+				(target.ID == pid.ID || strings.HasPrefix(target.ID, pid.ID+"/"))
+		})),
+	}
+}
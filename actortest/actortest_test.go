@@ -0,0 +1,121 @@
+package actortest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anthdm/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ping struct{ n int }
+
+type pong struct{ n int }
+
+func newEngine(t *testing.T) *actor.Engine {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	return e
+}
+
+func ponger(c *actor.Context) {
+	if msg, ok := c.Message().(ping); ok {
+		c.Respond(pong{n: msg.n})
+	}
+}
+
+func TestExpectMsg(t *testing.T) {
+	e := newEngine(t)
+	probe := NewTestProbe(t, e)
+	pid := e.SpawnFunc(ponger, "ponger")
+	probe.Send(pid, ping{n: 1})
+	probe.Send(pid, ping{n: 2})
+	assert.Equal(t, pong{n: 1}, ExpectMsg[pong](probe, time.Second))
+	assert.Equal(t, pong{n: 2}, ExpectMsg[pong](probe, time.Second))
+	probe.ExpectNoMsg(10 * time.Millisecond)
+	assert.Len(t, probe.Received(), 2)
+}
+
+func TestFishForMessage(t *testing.T) {
+	e := newEngine(t)
+	probe := NewTestProbe(t, e)
+	for i := 1; i <= 5; i++ {
+		e.Send(probe.PID(), ping{n: i})
+	}
+	msg := probe.FishForMessage(func(msg any) bool {
+		return msg.(ping).n == 4
+	}, time.Second)
+	assert.Equal(t, ping{n: 4}, msg)
+	assert.Equal(t, ping{n: 5}, ExpectMsg[ping](probe, time.Second))
+}
+
+func TestExpectTerminated(t *testing.T) {
+	e := newEngine(t)
+	probe := NewTestProbe(t, e)
+	pid := e.SpawnFunc(ponger, "ponger")
+	e.Poison(pid)
+	terminated := probe.ExpectTerminated(pid, time.Second)
+	assert.True(t, terminated.PID.Equals(pid))
+}
+
+func TestSpawnInterceptsSends(t *testing.T) {
+	e := newEngine(t)
+	other := NewTestProbe(t, e)
+	pid, probe := SpawnFunc(t, e, func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case ping:
+			c.SendWithHeaders(other.PID(), pong{n: msg.n}, map[string]string{"id": "1"})
+		case string:
+			resp, err := c.Request(other.PID(), ping{n: 3}, time.Second).Result()
+			if !assert.NoError(t, err) {
+				return
+			}
+			c.Respond(resp)
+		}
+	}, "forwarder")
+
+	probe.Send(pid, ping{n: 1})
+	msg := probe.Expect(time.Second)
+	assert.Equal(t, pong{n: 1}, msg.Msg)
+	assert.Equal(t, pid, msg.Sender)
+	assert.Equal(t, other.PID(), msg.Target)
+	assert.Equal(t, "1", msg.Headers["id"])
+
+	// the request is intercepted too, the probe responds in place of the target.
+	probe.Send(pid, "request")
+	assert.Equal(t, ping{n: 3}, ExpectMsg[ping](probe, time.Second))
+	probe.Respond(pong{n: 3})
+	msg = probe.Expect(time.Second)
+	assert.Equal(t, pong{n: 3}, msg.Msg)
+	assert.Equal(t, probe.PID(), msg.Target)
+	other.ExpectNoMsg(10 * time.Millisecond)
+}
+
+func TestSpawnDeliversToSelfAndChildren(t *testing.T) {
+	e := newEngine(t)
+	other := NewTestProbe(t, e)
+	pid, probe := SpawnFunc(t, e, func(c *actor.Context) {
+		switch msg := c.Message().(type) {
+		case ping:
+			// the child answers to its parent, through its own context.
+			child := c.SpawnChildFunc(func(c *actor.Context) {
+				if msg, ok := c.Message().(ping); ok {
+					c.Send(c.Parent(), pong{n: msg.n + 1})
+				}
+			}, "child")
+			c.Send(child, ping{n: msg.n + 1})
+		case pong:
+			c.Send(c.PID(), msg.n+1)
+		case int:
+			c.Send(other.PID(), msg)
+		}
+	}, "parent")
+
+	probe.Send(pid, ping{n: 1})
+	msg := probe.Expect(time.Second)
+	assert.Equal(t, 4, msg.Msg)
+	assert.Equal(t, other.PID(), msg.Target)
+	probe.ExpectNoMsg(10 * time.Millisecond)
+	other.ExpectNoMsg(10 * time.Millisecond)
+}
@@ -7,44 +7,10 @@ import (
 	"time"
 
 	"github.com/anthdm/hollywood/actor"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/anthdm/hollywood/metrics"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-type PromMetrics struct {
-	msgCounter prometheus.Counter
-	msgLatency prometheus.Histogram
-}
-
-func newPromMetrics(prefix string) *PromMetrics {
-	msgCounter := promauto.NewCounter(prometheus.CounterOpts{
-		Name: fmt.Sprintf("%s_actor_msg_counter", prefix),
-		Help: "counter of the messages the actor received",
-	})
-	msgLatency := promauto.NewHistogram(prometheus.HistogramOpts{
-		Name:    fmt.Sprintf("%s_actor_msg_latency", prefix),
-		Help:    "actor msg latency",
-		Buckets: []float64{0.1, 0.5, 1},
-	})
-	return &PromMetrics{
-		msgCounter: msgCounter,
-		msgLatency: msgLatency,
-	}
-}
-
-func (p *PromMetrics) WithMetrics() func(actor.ReceiveFunc) actor.ReceiveFunc {
-	return func(next actor.ReceiveFunc) actor.ReceiveFunc {
-		return func(c *actor.Context) {
-			start := time.Now()
-			p.msgCounter.Inc()
-			next(c)
-			ms := time.Since(start).Seconds()
-			p.msgLatency.Observe(ms)
-		}
-	}
-}
-
 type Message struct {
 	data string
 }
@@ -86,15 +52,18 @@ func main() {
 	go func() {
 		http.ListenAndServe(*promListenAddr, promhttp.Handler())
 	}()
-	e, err := actor.NewEngine(actor.NewEngineConfig())
+	m, err := metrics.New(metrics.NewConfig())
+	if err != nil {
+		panic(err)
+	}
+	e, err := actor.NewEngine(actor.NewEngineConfig().WithMiddleware(m.Middleware()))
 	if err != nil {
 		panic(err)
 	}
+	m.Attach(e)
 	var (
-		foometrics = newPromMetrics("foo")
-		barmetrics = newPromMetrics("bar")
-		fooPID     = e.Spawn(newFoo, "foo", actor.WithMiddleware(foometrics.WithMetrics()))
-		barPID     = e.Spawn(newBar, "bar", actor.WithMiddleware(barmetrics.WithMetrics()))
+		fooPID = e.Spawn(newFoo, "foo")
+		barPID = e.Spawn(newBar, "bar")
 	)
 
 	for i := 0; i < 10; i++ {
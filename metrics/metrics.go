@@ -0,0 +1,274 @@
+// Package metrics exports the metrics of an actor engine and its remote to
+// Prometheus. All the actor metrics are labeled by kind instead of PID, which
+// keeps the cardinality bounded no matter how many actors are spawned.
+package metrics
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthdm/hollywood/actor"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultNamespace = "hollywood"
+	// unknownPeer is the peer label of the messages received without sender.
+	unknownPeer = "unknown"
+)
+
+// Config holds the configuration of the metrics.
+type Config struct {
+	namespace  string
+	registerer prometheus.Registerer
+	buckets    []float64
+}
+
+// NewConfig returns a new default Config, which registers the metrics in the
+// prometheus.DefaultRegisterer under the "hollywood" namespace.
+func NewConfig() Config {
+	return Config{
+		namespace:  defaultNamespace,
+		registerer: prometheus.DefaultRegisterer,
+		buckets:    prometheus.DefBuckets,
+	}
+}
+
+// WithNamespace sets the namespace of the metrics.
+func (c Config) WithNamespace(namespace string) Config {
+	c.namespace = namespace
+	return c
+}
+
+// WithRegisterer sets the registerer the metrics are registered in.
+func (c Config) WithRegisterer(r prometheus.Registerer) Config {
+	c.registerer = r
+	return c
+}
+
+// WithBuckets sets the buckets, in seconds, of the processing latency
+// histogram.
+func (c Config) WithBuckets(buckets []float64) Config {
+	c.buckets = buckets
+	return c
+}
+
+// Metrics collects the metrics of an engine. The message counters and the
+// processing latencies are collected by the Middleware, the remote traffic by
+// using Metrics as the remote.Observer, and the rest once the engine is
+// attached:
+//
+//	m, err := metrics.New(metrics.NewConfig())
+//	r := remote.New(addr, remote.NewConfig().WithObserver(m))
+//	e, err := actor.NewEngine(actor.NewEngineConfig().
+//		WithRemote(r).
+//		WithMiddleware(m.Middleware()))
+//	m.Attach(e)
+type Metrics struct {
+	messages    *prometheus.CounterVec
+	latency     *prometheus.HistogramVec
+	restarts    *prometheus.CounterVec
+	deadLetters *prometheus.CounterVec
+	sentMsgs    *prometheus.CounterVec
+	sentBytes   *prometheus.CounterVec
+	recvMsgs    *prometheus.CounterVec
+	recvBytes   *prometheus.CounterVec
+	inbox       *inboxCollector
+}
+
+// New returns new Metrics, registered with the registerer of the given
+// Config.
+func New(config Config) (*Metrics, error) {
+	ns := config.namespace
+	m := &Metrics{
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "actor_messages_total",
+			Help:      "Number of messages received by the actors.",
+		}, []string{"kind"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: ns,
+			Name:      "actor_message_duration_seconds",
+			Help:      "Time spent by the actors processing a message.",
+			Buckets:   config.buckets,
+		}, []string{"kind"}),
+		restarts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "actor_restarts_total",
+			Help:      "Number of restarts of the actors.",
+		}, []string{"kind"}),
+		deadLetters: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "deadletters_total",
+			Help:      "Number of deadletters by kind of their target.",
+		}, []string{"kind"}),
+		sentMsgs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "remote_sent_messages_total",
+			Help:      "Number of messages sent to remote peers.",
+		}, []string{"peer"}),
+		sentBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "remote_sent_bytes_total",
+			Help:      "Size of the serialized messages sent to remote peers.",
+		}, []string{"peer"}),
+		recvMsgs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "remote_received_messages_total",
+			Help:      "Number of messages received from remote peers.",
+		}, []string{"peer"}),
+		recvBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: ns,
+			Name:      "remote_received_bytes_total",
+			Help:      "Size of the serialized messages received from remote peers.",
+		}, []string{"peer"}),
+		inbox: newInboxCollector(ns),
+	}
+	collectors := []prometheus.Collector{
+		m.messages, m.latency, m.restarts, m.deadLetters,
+		m.sentMsgs, m.sentBytes, m.recvMsgs, m.recvBytes, m.inbox,
+	}
+	for _, c := range collectors {
+		if err := config.registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Middleware returns the middleware that counts the messages received by the
+// actors and measures their processing latency. It is meant to be set on the
+// whole engine with actor.EngineConfig.WithMiddleware, but can be set on
+// single actors with actor.WithMiddleware as well.
+func (m *Metrics) Middleware() actor.MiddlewareFunc {
+	return func(next actor.ReceiveFunc) actor.ReceiveFunc {
+		return func(c *actor.Context) {
+			kind := Kind(c.PID())
+			m.messages.WithLabelValues(kind).Inc()
+			start := time.Now()
+			next(c)
+			m.latency.WithLabelValues(kind).Observe(time.Since(start).Seconds())
+		}
+	}
+}
+
+// Attach starts collecting the restarts, the deadletters and the inbox depths
+// of the given engine. It returns a function that detaches the engine again.
+func (m *Metrics) Attach(e *actor.Engine) (detach func()) {
+	unsubRestarts := actor.SubscribeFunc(e, func(ev actor.ActorRestartedEvent) {
+		m.restarts.WithLabelValues(Kind(ev.PID)).Inc()
+	})
+	unsubDeadLetters := actor.SubscribeFunc(e, func(ev actor.DeadLetterEvent) {
+		m.deadLetters.WithLabelValues(Kind(ev.Target)).Inc()
+	})
+	m.inbox.attach(e)
+	return func() {
+		unsubRestarts()
+		unsubDeadLetters()
+		m.inbox.detach(e)
+	}
+}
+
+// Sent implements remote.Observer.
+func (m *Metrics) Sent(peer string, msgs int, bytes int) {
+	m.sentMsgs.WithLabelValues(peer).Add(float64(msgs))
+	m.sentBytes.WithLabelValues(peer).Add(float64(bytes))
+}
+
+// Received implements remote.Observer.
+func (m *Metrics) Received(peer string, msgs int, bytes int) {
+	if peer == "" {
+		peer = unknownPeer
+	}
+	m.recvMsgs.WithLabelValues(peer).Add(float64(msgs))
+	m.recvBytes.WithLabelValues(peer).Add(float64(bytes))
+}
+
+// Kind returns the kind of the given PID, which is the ID without the
+// instance IDs. The kind of a child includes the kind of its parents, hence
+// the child "foo" of "bar/1" spawned as "bar/1/foo/2" is of kind "bar/foo".
+func Kind(pid *actor.PID) string {
+	if pid == nil {
+		return ""
+	}
+	i := strings.IndexByte(pid.ID, '/')
+	if i < 0 {
+		return pid.ID
+	}
+	// top-level processes, "kind/id", are the common case.
+	if strings.IndexByte(pid.ID[i+1:], '/') < 0 {
+		return pid.ID[:i]
+	}
+	parts := strings.Split(pid.ID, "/")
+	kinds := make([]string, 0, (len(parts)+1)/2)
+	for i := 0; i < len(parts); i += 2 {
+		kinds = append(kinds, parts[i])
+	}
+	return strings.Join(kinds, "/")
+}
+
+// inboxCollector collects the number of actors and messages waiting in
+// their inboxes per kind, at the time of the scrape.
+type inboxCollector struct {
+	mu        sync.Mutex
+	engines   []*actor.Engine
+	inbox     *prometheus.Desc
+	processes *prometheus.Desc
+}
+
+func newInboxCollector(ns string) *inboxCollector {
+	return &inboxCollector{
+		inbox: prometheus.NewDesc(prometheus.BuildFQName(ns, "actor", "inbox_messages"),
+			"Number of messages waiting in the inboxes of the actors.", []string{"kind"}, nil),
+		processes: prometheus.NewDesc(prometheus.BuildFQName(ns, "actor", "processes"),
+			"Number of running actors.", []string{"kind"}, nil),
+	}
+}
+
+func (c *inboxCollector) attach(e *actor.Engine) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.engines = append(c.engines, e)
+}
+
+func (c *inboxCollector) detach(e *actor.Engine) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, engine := range c.engines {
+		if engine == e {
+			c.engines = append(c.engines[:i], c.engines[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *inboxCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.inbox
+	ch <- c.processes
+}
+
+func (c *inboxCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	engines := append([]*actor.Engine(nil), c.engines...)
+	c.mu.Unlock()
+
+	type stats struct{ inbox, processes int }
+	kinds := make(map[string]*stats)
+	for _, e := range engines {
+		e.InboxLens(func(pid *actor.PID, n int) {
+			kind := Kind(pid)
+			s, ok := kinds[kind]
+			if !ok {
+				s = &stats{}
+				kinds[kind] = s
+			}
+			s.inbox += n
+			s.processes++
+		})
+	}
+	for kind, s := range kinds {
+		ch <- prometheus.MustNewConstMetric(c.inbox, prometheus.GaugeValue, float64(s.inbox), kind)
+		ch <- prometheus.MustNewConstMetric(c.processes, prometheus.GaugeValue, float64(s.processes), kind)
+	}
+}
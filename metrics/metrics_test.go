@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthdm/hollywood/actor"
+	"github.com/anthdm/hollywood/remote"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetrics(t *testing.T) (*Metrics, *prometheus.Registry) {
+	reg := prometheus.NewRegistry()
+	m, err := New(NewConfig().WithRegisterer(reg))
+	require.NoError(t, err)
+	return m, reg
+}
+
+func TestKind(t *testing.T) {
+	assert.Equal(t, "foo", Kind(actor.NewPID("local", "foo/1")))
+	assert.Equal(t, "foo/bar", Kind(actor.NewPID("local", "foo/1/bar/2")))
+	assert.Equal(t, "stream", Kind(actor.NewPID("local", "stream/127.0.0.1:4000")))
+	assert.Equal(t, "single", Kind(actor.NewPID("local", "single")))
+	assert.Equal(t, "", Kind(nil))
+}
+
+func TestMessagesAndLatency(t *testing.T) {
+	m, _ := newMetrics(t)
+	e, err := actor.NewEngine(actor.NewEngineConfig().WithMiddleware(m.Middleware()))
+	require.NoError(t, err)
+	m.Attach(e)
+
+	wg := sync.WaitGroup{}
+	wg.Add(4)
+	receive := func(c *actor.Context) {
+		if _, ok := c.Message().(string); ok {
+			wg.Done()
+		}
+	}
+	foo1 := e.SpawnFunc(receive, "foo")
+	foo2 := e.SpawnFunc(receive, "foo")
+	bar := e.SpawnFunc(receive, "bar")
+	e.Send(foo1, "a")
+	e.Send(foo2, "b")
+	e.Send(foo2, "c")
+	e.Send(bar, "d")
+	wg.Wait()
+
+	// Initialized and Started are received by every actor as well.
+	assert.Equal(t, float64(2*2+3), testutil.ToFloat64(m.messages.WithLabelValues("foo")))
+	assert.Equal(t, float64(2+1), testutil.ToFloat64(m.messages.WithLabelValues("bar")))
+	// the engine actors are measured as well.
+	assert.GreaterOrEqual(t, testutil.CollectAndCount(m.latency, "hollywood_actor_message_duration_seconds"), 2)
+}
+
+func TestRestartsAndDeadLetters(t *testing.T) {
+	m, _ := newMetrics(t)
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	detach := m.Attach(e)
+	defer detach()
+
+	crashed := false
+	pid := e.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(string); ok && !crashed {
+			crashed = true
+			panic("crash")
+		}
+	}, "crasher", actor.WithRestartDelay(0))
+	e.Send(pid, "crash")
+	e.Send(actor.NewPID(e.Address(), "ghost/1"), "boo")
+
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(m.restarts.WithLabelValues("crasher")) == 1 &&
+			testutil.ToFloat64(m.deadLetters.WithLabelValues("ghost")) == 1
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestInboxDepth(t *testing.T) {
+	m, reg := newMetrics(t)
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	m.Attach(e)
+
+	block := make(chan struct{})
+	defer close(block)
+	started := make(chan struct{})
+	pid := e.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(int); ok {
+			if msg == 0 {
+				close(started)
+			}
+			<-block
+		}
+	}, "slow")
+	e.SpawnFunc(func(c *actor.Context) {}, "slow")
+	e.Send(pid, 0)
+	<-started
+	for i := 1; i < 4; i++ {
+		e.Send(pid, i)
+	}
+
+	assert.Equal(t, float64(3), gauge(t, reg, "hollywood_actor_inbox_messages", "slow"))
+	assert.Equal(t, float64(2), gauge(t, reg, "hollywood_actor_processes", "slow"))
+}
+
+// gauge returns the value of the gauge with the given name and kind.
+func gauge(t *testing.T, reg *prometheus.Registry, name, kind string) float64 {
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			if metric.GetLabel()[0].GetValue() == kind {
+				return metric.GetGauge().GetValue()
+			}
+		}
+	}
+	t.Fatalf("gauge %s{kind=%q} not found", name, kind)
+	return 0
+}
+
+func TestRemoteTraffic(t *testing.T) {
+	m, _ := newMetrics(t)
+	addrA := fmt.Sprintf("127.0.0.1:%d", rand.Intn(50000)+10000)
+	addrB := fmt.Sprintf("127.0.0.1:%d", rand.Intn(50000)+10000)
+	a, err := actor.NewEngine(actor.NewEngineConfig().
+		WithRemote(remote.New(addrA, remote.NewConfig().WithObserver(m))))
+	require.NoError(t, err)
+	b, err := actor.NewEngine(actor.NewEngineConfig().
+		WithRemote(remote.New(addrB, remote.NewConfig().WithObserver(m))))
+	require.NoError(t, err)
+
+	wg := sync.WaitGroup{}
+	wg.Add(3)
+	target := b.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(*actor.PID); ok {
+			wg.Done()
+		}
+	}, "target")
+	sender := a.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*actor.PID); ok {
+			c.Send(target, msg)
+		}
+	}, "sender")
+	for i := 0; i < 3; i++ {
+		a.Send(sender, actor.NewPID("foo", "bar"))
+	}
+	wg.Wait()
+
+	// the writer observes the batch once it was sent, which may be after the
+	// target received it.
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(m.sentMsgs.WithLabelValues(addrB)) == 3
+	}, time.Second, time.Millisecond*10)
+	assert.Equal(t, float64(3), testutil.ToFloat64(m.recvMsgs.WithLabelValues(addrA)))
+	assert.Greater(t, testutil.ToFloat64(m.sentBytes.WithLabelValues(addrB)), float64(0))
+	assert.Equal(t, testutil.ToFloat64(m.sentBytes.WithLabelValues(addrB)),
+		testutil.ToFloat64(m.recvBytes.WithLabelValues(addrA)))
+}
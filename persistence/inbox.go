@@ -0,0 +1,341 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/anthdm/hollywood/actor"
+)
+
+// MemoryInboxStore is an actor.InboxStore that keeps the messages in memory,
+// they survive the crashes and respawns of the actors but not the process.
+type MemoryInboxStore struct {
+	mu      sync.Mutex
+	inboxes map[string]*memoryInbox
+}
+
+type memoryInbox struct {
+	seq     uint64
+	entries []actor.InboxEntry
+}
+
+// NewMemoryInboxStore returns a new empty MemoryInboxStore.
+func NewMemoryInboxStore() *MemoryInboxStore {
+	return &MemoryInboxStore{inboxes: make(map[string]*memoryInbox)}
+}
+
+func (s *MemoryInboxStore) Append(id string, env actor.Envelope) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inbox, ok := s.inboxes[id]
+	if !ok {
+		inbox = &memoryInbox{}
+		s.inboxes[id] = inbox
+	}
+	inbox.seq++
+	inbox.entries = append(inbox.entries, actor.InboxEntry{Seq: inbox.seq, Envelope: env})
+	return inbox.seq, nil
+}
+
+func (s *MemoryInboxStore) Remove(id string, seq uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inbox, ok := s.inboxes[id]
+	if !ok {
+		return nil
+	}
+	for i, entry := range inbox.entries {
+		if entry.Seq == seq {
+			inbox.entries = append(inbox.entries[:i], inbox.entries[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *MemoryInboxStore) Pending(id string) ([]actor.InboxEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inbox, ok := s.inboxes[id]
+	if !ok {
+		return nil, nil
+	}
+	return append([]actor.InboxEntry(nil), inbox.entries...), nil
+}
+
+// inboxRecord is an encoded envelope of a FileInboxStore.
+type inboxRecord struct {
+	Msg           []byte
+	SenderAddress string
+	SenderID      string
+	TraceID       actor.TraceID
+	SpanID        actor.SpanID
+	Headers       map[string]string
+}
+
+// FileInboxStore is an actor.InboxStore that appends the messages to one file
+// per actor ID in a directory, in the record format of the FileJournal. A
+// removal appends an empty record with the sequence number of the message.
+// Each append of a message is synced to disk, removals are not, hence a crash
+// may deliver a message again. The file is emptied whenever all its messages
+// were removed, and compacted when the pending messages are loaded.
+type FileInboxStore struct {
+	dir     string
+	codec   Codec
+	mu      sync.Mutex
+	inboxes map[string]*fileInbox
+}
+
+type fileInbox struct {
+	f       *os.File
+	seq     uint64
+	pending map[uint64]struct{}
+}
+
+// NewFileInboxStore returns a FileInboxStore that stores the messages in the
+// given directory, which is created if it does not exist. The messages are
+// encoded with the given codec.
+func NewFileInboxStore(dir string, codec Codec) (*FileInboxStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileInboxStore{dir: dir, codec: codec, inboxes: make(map[string]*fileInbox)}, nil
+}
+
+func (s *FileInboxStore) Append(id string, env actor.Envelope) (uint64, error) {
+	data, err := s.encode(env)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inbox, err := s.inbox(id)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeRecord(inbox.f, inbox.seq+1, data); err != nil {
+		return 0, err
+	}
+	if err := inbox.f.Sync(); err != nil {
+		return 0, err
+	}
+	inbox.seq++
+	inbox.pending[inbox.seq] = struct{}{}
+	return inbox.seq, nil
+}
+
+func (s *FileInboxStore) Remove(id string, seq uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inbox, err := s.inbox(id)
+	if err != nil {
+		return err
+	}
+	if _, ok := inbox.pending[seq]; !ok {
+		return nil
+	}
+	delete(inbox.pending, seq)
+	if len(inbox.pending) == 0 {
+		return truncate(inbox.f, 0)
+	}
+	return writeRecord(inbox.f, seq, nil)
+}
+
+func (s *FileInboxStore) Pending(id string) ([]actor.InboxEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inbox, err := s.inbox(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := inbox.f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	seqs, records, removed, err := readInbox(inbox.f)
+	if err != nil {
+		return nil, err
+	}
+	if removed > 0 {
+		err = s.compact(id, inbox, seqs, records)
+	} else {
+		_, err = inbox.f.Seek(0, io.SeekEnd)
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]actor.InboxEntry, len(seqs))
+	for i, seq := range seqs {
+		env, err := s.decode(records[seq])
+		if err != nil {
+			return nil, fmt.Errorf("persistence: decode message %d of %q: %w", seq, id, err)
+		}
+		entries[i] = actor.InboxEntry{Seq: seq, Envelope: env}
+	}
+	return entries, nil
+}
+
+// Close closes the files of the store.
+func (s *FileInboxStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for id, inbox := range s.inboxes {
+		errs = append(errs, inbox.f.Close())
+		delete(s.inboxes, id)
+	}
+	return errors.Join(errs...)
+}
+
+// inbox returns the inbox of the given ID, with its file opened for appending
+// after the last complete record.
+func (s *FileInboxStore) inbox(id string) (*fileInbox, error) {
+	if inbox, ok := s.inboxes[id]; ok {
+		return inbox, nil
+	}
+	f, err := os.OpenFile(s.path(id), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	inbox := &fileInbox{f: f, pending: make(map[uint64]struct{})}
+	seqs, _, _, err := readInbox(f)
+	if err == nil {
+		for _, seq := range seqs {
+			inbox.pending[seq] = struct{}{}
+			inbox.seq = seq
+		}
+		var end int64
+		end, err = f.Seek(0, io.SeekCurrent)
+		if err == nil {
+			err = truncate(f, end)
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.inboxes[id] = inbox
+	return inbox, nil
+}
+
+// compact replaces the file of the inbox with one that only holds the given
+// pending records.
+func (s *FileInboxStore) compact(id string, inbox *fileInbox, seqs []uint64, records map[uint64][]byte) error {
+	f, err := os.CreateTemp(s.dir, "inbox-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	for _, seq := range seqs {
+		if err = writeRecord(f, seq, records[seq]); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), s.path(id))
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	inbox.f.Close()
+	inbox.f = f
+	return nil
+}
+
+func (s *FileInboxStore) encode(env actor.Envelope) ([]byte, error) {
+	msg, err := s.codec.Encode(env.Msg)
+	if err != nil {
+		return nil, fmt.Errorf("persistence: encode message %T: %w", env.Msg, err)
+	}
+	record := inboxRecord{
+		Msg:     msg,
+		TraceID: env.Trace.TraceID,
+		SpanID:  env.Trace.SpanID,
+		Headers: env.Headers,
+	}
+	if env.Sender != nil {
+		record.SenderAddress = env.Sender.Address
+		record.SenderID = env.Sender.ID
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(record); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *FileInboxStore) decode(data []byte) (actor.Envelope, error) {
+	var record inboxRecord
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&record); err != nil {
+		return actor.Envelope{}, err
+	}
+	msg, err := s.codec.Decode(record.Msg)
+	if err != nil {
+		return actor.Envelope{}, err
+	}
+	env := actor.Envelope{
+		Msg:     msg,
+		Trace:   actor.SpanContext{TraceID: record.TraceID, SpanID: record.SpanID},
+		Headers: record.Headers,
+	}
+	if record.SenderID != "" {
+		env.Sender = actor.NewPID(record.SenderAddress, record.SenderID)
+	}
+	return env, nil
+}
+
+func (s *FileInboxStore) path(id string) string {
+	return filepath.Join(s.dir, url.PathEscape(id)+".inbox")
+}
+
+// readInbox reads the records of an inbox file up to the last complete one,
+// where the file is left. It returns the sequence numbers of the pending
+// records, in order, their data and the number of removed records.
+func readInbox(f *os.File) ([]uint64, map[uint64][]byte, int, error) {
+	var (
+		seqs    []uint64
+		records = make(map[uint64][]byte)
+		removed int
+	)
+	end, err := scanRecords(f, func(seq uint64, data []byte) error {
+		if len(data) == 0 {
+			delete(records, seq)
+			removed++
+			return nil
+		}
+		seqs = append(seqs, seq)
+		records[seq] = data
+		return nil
+	})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		return nil, nil, 0, err
+	}
+	pending := seqs[:0]
+	for _, seq := range seqs {
+		if _, ok := records[seq]; ok {
+			pending = append(pending, seq)
+		}
+	}
+	return pending, records, removed, nil
+}
+
+func truncate(f *os.File, size int64) error {
+	if err := f.Truncate(size); err != nil {
+		return err
+	}
+	_, err := f.Seek(size, io.SeekStart)
+	return err
+}
@@ -0,0 +1,278 @@
+package persistence
+
+import (
+	"encoding/gob"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthdm/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type job struct {
+	N int
+}
+
+func init() {
+	gob.Register(job{})
+}
+
+type worker struct {
+	crashOn int
+	// crashed, if set, makes the worker crash only once on crashOn.
+	crashed *atomic.Bool
+	block   chan struct{}
+	done    chan int
+}
+
+func (w *worker) Receive(ctx *actor.Context) {
+	if msg, ok := ctx.Message().(job); ok {
+		if msg.N == w.crashOn && (w.crashed == nil || !w.crashed.Swap(true)) {
+			panic("crash on job")
+		}
+		if w.block != nil {
+			<-w.block
+		}
+		w.done <- msg.N
+	}
+}
+
+func receive(t *testing.T, done chan int, n int) []int {
+	var jobs []int
+	for i := 0; i < n; i++ {
+		select {
+		case job := <-done:
+			jobs = append(jobs, job)
+		case <-time.After(time.Second):
+			t.Fatalf("received %v, expected %d jobs", jobs, n)
+		}
+	}
+	return jobs
+}
+
+func TestDurableInboxSurvivesCrash(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	store := NewMemoryInboxStore()
+	done := make(chan int, 10)
+	spawn := func(crashOn int) *actor.PID {
+		return e.Spawn(func() actor.Receiver {
+			return &worker{crashOn: crashOn, done: done}
+		}, "worker", actor.WithID("1"), actor.WithDurableInbox(store), actor.WithMaxRestarts(0))
+	}
+	pid := spawn(2)
+	for i := 1; i <= 3; i++ {
+		e.Send(pid, job{N: i})
+	}
+	assert.Equal(t, []int{1}, receive(t, done, 1))
+	require.Eventually(t, func() bool {
+		return e.Registry.GetPID("worker", "1") == nil
+	}, time.Second, time.Millisecond)
+
+	// the crashing job and the job after it are still pending.
+	spawn(0)
+	assert.Equal(t, []int{2, 3}, receive(t, done, 2))
+	require.Eventually(t, func() bool {
+		entries, err := store.Pending(pid.ID)
+		return err == nil && len(entries) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestDurableInboxRedeliversCrashingMessage(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	store := NewMemoryInboxStore()
+	done := make(chan int, 10)
+	crashed := &atomic.Bool{}
+	pid := e.Spawn(func() actor.Receiver {
+		return &worker{crashOn: 2, crashed: crashed, done: done}
+	}, "worker", actor.WithID("1"), actor.WithDurableInbox(store), actor.WithMaxRestarts(3), actor.WithRestartDelay(0))
+	for i := 1; i <= 3; i++ {
+		e.Send(pid, job{N: i})
+	}
+	// the crashing job is received again first after the restart.
+	assert.Equal(t, []int{1, 2, 3}, receive(t, done, 3))
+	require.Eventually(t, func() bool {
+		entries, err := store.Pending(pid.ID)
+		return err == nil && len(entries) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestDurableInboxResumeSkipsCrashingMessage(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	store := NewMemoryInboxStore()
+	done := make(chan int, 10)
+	pid := e.Spawn(func() actor.Receiver {
+		return &worker{crashOn: 2, done: done}
+	}, "worker", actor.WithID("1"), actor.WithDurableInbox(store), actor.WithSupervisor(actor.NewOneForOneStrategy(
+		func(any) actor.Directive { return actor.ResumeDirective },
+	)))
+	for i := 1; i <= 3; i++ {
+		e.Send(pid, job{N: i})
+	}
+	assert.Equal(t, []int{1, 3}, receive(t, done, 2))
+	require.Eventually(t, func() bool {
+		entries, err := store.Pending(pid.ID)
+		return err == nil && len(entries) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestDurableInboxBlockedSendersDontWaitForEachOther(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	done := make(chan int, 10)
+	block := make(chan struct{})
+	defer close(block)
+	const timeout = 100 * time.Millisecond
+	pid := e.Spawn(func() actor.Receiver {
+		return &worker{block: block, done: done}
+	}, "worker", actor.WithDurableInbox(NewMemoryInboxStore()), actor.WithInboxSize(1),
+		actor.WithOverflowPolicy(actor.OverflowBlock), actor.WithBlockTimeout(timeout))
+	// the worker blocks on the first job and the second one fills the inbox.
+	e.Send(pid, job{N: 1})
+	time.Sleep(10 * time.Millisecond)
+	e.Send(pid, job{N: 2})
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 3; i <= 4; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			e.Send(pid, job{N: n})
+		}(i)
+	}
+	wg.Wait()
+	// both senders time out together, not one after the other.
+	assert.Less(t, time.Since(start), 2*timeout)
+}
+
+func TestDurableInboxSurvivesEngine(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileInboxStore(dir, GobCodec{})
+	require.NoError(t, err)
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	done := make(chan int, 10)
+	block := make(chan struct{})
+	defer close(block)
+	pid := e.Spawn(func() actor.Receiver {
+		return &worker{block: block, done: done}
+	}, "worker", actor.WithID("1"), actor.WithDurableInbox(store))
+	sender := actor.NewPID("127.0.0.1:4000", "sender/1")
+	for i := 1; i <= 3; i++ {
+		e.SendWithSender(pid, job{N: i}, sender)
+	}
+	// the engine goes down while the first job is received.
+	require.Eventually(t, func() bool {
+		entries, err := store.Pending(pid.ID)
+		return err == nil && len(entries) == 3
+	}, time.Second, time.Millisecond)
+
+	store, err = NewFileInboxStore(dir, GobCodec{})
+	require.NoError(t, err)
+	defer store.Close()
+	entries, err := store.Pending(pid.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, job{N: 1}, entries[0].Envelope.Msg)
+	assert.Equal(t, sender, entries[0].Envelope.Sender)
+
+	e, err = actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	e.Spawn(func() actor.Receiver {
+		return &worker{done: done}
+	}, "worker", actor.WithID("1"), actor.WithDurableInbox(store))
+	assert.Equal(t, []int{1, 2, 3}, receive(t, done, 3))
+}
+
+func testInboxStore(t *testing.T, s actor.InboxStore) {
+	for i := 1; i <= 3; i++ {
+		seq, err := s.Append("worker/1", actor.Envelope{Msg: job{N: i}})
+		require.NoError(t, err)
+		assert.Equal(t, uint64(i), seq)
+	}
+	_, err := s.Append("worker/2", actor.Envelope{Msg: job{N: 4}})
+	require.NoError(t, err)
+	require.NoError(t, s.Remove("worker/1", 2))
+
+	entries, err := s.Pending("worker/1")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, uint64(1), entries[0].Seq)
+	assert.Equal(t, job{N: 1}, entries[0].Envelope.Msg)
+	assert.Equal(t, uint64(3), entries[1].Seq)
+	assert.Equal(t, job{N: 3}, entries[1].Envelope.Msg)
+
+	require.NoError(t, s.Remove("worker/1", 1))
+	require.NoError(t, s.Remove("worker/1", 3))
+	entries, err = s.Pending("worker/1")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	entries, err = s.Pending("missing")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestMemoryInboxStore(t *testing.T) {
+	testInboxStore(t, NewMemoryInboxStore())
+}
+
+func TestFileInboxStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileInboxStore(dir, GobCodec{})
+	require.NoError(t, err)
+	testInboxStore(t, s)
+	_, err = s.Append("worker/1", actor.Envelope{Msg: job{N: 5}})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	s, err = NewFileInboxStore(dir, GobCodec{})
+	require.NoError(t, err)
+	defer s.Close()
+	entries, err := s.Pending("worker/1")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, job{N: 5}, entries[0].Envelope.Msg)
+}
+
+func TestDurableInboxKeepsStashedMessages(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	store := NewMemoryInboxStore()
+	done := make(chan int, 10)
+	pid := e.SpawnFunc(func(c *actor.Context) {
+		switch c.Message().(type) {
+		case job:
+			c.Stash()
+		case getTotal:
+			c.Respond(true)
+		case string:
+			c.UnstashAll()
+			c.Become(func(c *actor.Context) {
+				if msg, ok := c.Message().(job); ok {
+					done <- msg.N
+				}
+			})
+		}
+	}, "worker", actor.WithID("1"), actor.WithDurableInbox(store))
+	e.Send(pid, job{N: 1})
+	e.Send(pid, job{N: 2})
+	_, err = e.Request(pid, getTotal{}, time.Second).Result()
+	require.NoError(t, err)
+	// the stashed jobs are still pending.
+	entries, err := store.Pending(pid.ID)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	e.Send(pid, "ready")
+	assert.Equal(t, []int{1, 2}, receive(t, done, 2))
+	require.Eventually(t, func() bool {
+		entries, err := store.Pending(pid.ID)
+		return err == nil && len(entries) == 0
+	}, time.Second, time.Millisecond)
+}
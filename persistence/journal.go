@@ -0,0 +1,216 @@
+package persistence
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Journal stores the events of persistent actors, identified by their
+// persistence ID. Sequence numbers start at 1 and increase by one for each
+// event.
+type Journal interface {
+	// Append appends the given event with the given sequence number.
+	Append(id string, seq uint64, data []byte) error
+	// Replay calls fn for each event of the given ID, in order, starting at
+	// the given sequence number. Replay stops at the first error of fn.
+	Replay(id string, fromSeq uint64, fn func(seq uint64, data []byte) error) error
+}
+
+type event struct {
+	seq  uint64
+	data []byte
+}
+
+// MemoryJournal is a Journal that keeps the events in memory, they survive
+// the restarts and respawns of the actors but not the process.
+type MemoryJournal struct {
+	mu     sync.RWMutex
+	events map[string][]event
+}
+
+// NewMemoryJournal returns a new empty MemoryJournal.
+func NewMemoryJournal() *MemoryJournal {
+	return &MemoryJournal{events: make(map[string][]event)}
+}
+
+func (j *MemoryJournal) Append(id string, seq uint64, data []byte) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.events[id] = append(j.events[id], event{seq: seq, data: append([]byte(nil), data...)})
+	return nil
+}
+
+func (j *MemoryJournal) Replay(id string, fromSeq uint64, fn func(uint64, []byte) error) error {
+	j.mu.RLock()
+	events := j.events[id]
+	j.mu.RUnlock()
+	for _, ev := range events {
+		if ev.seq < fromSeq {
+			continue
+		}
+		if err := fn(ev.seq, ev.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileJournalHeaderSize is the size of the header of a record: the length of
+// the data, the sequence number and the CRC-32 of both and the data.
+const fileJournalHeaderSize = 4 + 8 + 4
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// FileJournal is a Journal that appends the events to one file per
+// persistence ID in a directory. Each append is synced to disk. A record that
+// was partially written by a crash, or that doesn't match its checksum, is
+// ignored on replay together with all the records after it, and overwritten
+// by the next append.
+type FileJournal struct {
+	dir   string
+	mu    sync.Mutex
+	files map[string]*os.File
+}
+
+// NewFileJournal returns a FileJournal that stores the events in the given
+// directory, which is created if it does not exist.
+func NewFileJournal(dir string) (*FileJournal, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileJournal{dir: dir, files: make(map[string]*os.File)}, nil
+}
+
+func (j *FileJournal) Append(id string, seq uint64, data []byte) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	f, err := j.file(id)
+	if err != nil {
+		return err
+	}
+	if err := writeRecord(f, seq, data); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// file returns the file of the given ID, opened for appending after the last
+// complete record.
+func (j *FileJournal) file(id string) (*os.File, error) {
+	if f, ok := j.files[id]; ok {
+		return f, nil
+	}
+	f, err := os.OpenFile(j.path(id), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	end, err := scanRecords(f, func(uint64, []byte) error { return nil })
+	if err == nil {
+		_, err = f.Seek(end, io.SeekStart)
+	}
+	if err == nil {
+		err = f.Truncate(end)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	j.files[id] = f
+	return f, nil
+}
+
+func (j *FileJournal) Replay(id string, fromSeq uint64, fn func(uint64, []byte) error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	f, err := os.Open(j.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = scanRecords(f, func(seq uint64, data []byte) error {
+		if seq < fromSeq {
+			return nil
+		}
+		return fn(seq, data)
+	})
+	return err
+}
+
+// Close closes the files of the journal.
+func (j *FileJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var errs []error
+	for id, f := range j.files {
+		errs = append(errs, f.Close())
+		delete(j.files, id)
+	}
+	return errors.Join(errs...)
+}
+
+func (j *FileJournal) path(id string) string {
+	return filepath.Join(j.dir, url.PathEscape(id)+".journal")
+}
+
+// writeRecord writes a record with the given sequence number and data.
+func writeRecord(w io.Writer, seq uint64, data []byte) error {
+	record := make([]byte, fileJournalHeaderSize+len(data))
+	binary.BigEndian.PutUint32(record, uint32(len(data)))
+	binary.BigEndian.PutUint64(record[4:], seq)
+	copy(record[fileJournalHeaderSize:], data)
+	binary.BigEndian.PutUint32(record[12:], recordChecksum(record[:12], data))
+	_, err := w.Write(record)
+	return err
+}
+
+func recordChecksum(header, data []byte) uint32 {
+	return crc32.Update(crc32.Checksum(header, crcTable), crcTable, data)
+}
+
+// scanRecords calls fn for each valid record of the given reader, up to the
+// first one that is incomplete or doesn't match its checksum, and returns the
+// offset after the last valid record.
+func scanRecords(r io.Reader, fn func(seq uint64, data []byte) error) (int64, error) {
+	var (
+		br     = bufio.NewReader(r)
+		header = make([]byte, fileJournalHeaderSize)
+		offset int64
+	)
+	for {
+		if _, err := io.ReadFull(br, header); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return offset, nil
+			}
+			return offset, err
+		}
+		// the length may be corrupted, hence the data is only allocated as
+		// far as it can be read.
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, br, int64(binary.BigEndian.Uint32(header))); err != nil {
+			if errors.Is(err, io.EOF) {
+				return offset, nil
+			}
+			return offset, err
+		}
+		data := buf.Bytes()
+		if recordChecksum(header[:12], data) != binary.BigEndian.Uint32(header[12:]) {
+			return offset, nil
+		}
+		if err := fn(binary.BigEndian.Uint64(header[4:]), data); err != nil {
+			return offset, fmt.Errorf("replay: %w", err)
+		}
+		offset += int64(fileJournalHeaderSize + len(data))
+	}
+}
@@ -0,0 +1,222 @@
+// Package persistence makes actors durable by event sourcing. A persistent
+// actor persists an event for every change of its state, which is appended to
+// a Journal before the state is changed. When the actor is initialized, after
+// a crash or a respawn with the same PID, its state is recovered from the
+// latest snapshot and the events persisted afterwards.
+package persistence
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/anthdm/hollywood/actor"
+)
+
+// ErrNotRecovered is the reason of the panic of Persist when it is called
+// before the actor was recovered, which happens on actor.Initialized.
+var ErrNotRecovered = errors.New("persistence: actor not recovered")
+
+// Codec encodes the events and the snapshots.
+type Codec interface {
+	Encode(v any) ([]byte, error)
+	Decode(data []byte) (any, error)
+}
+
+// GobCodec encodes the values with encoding/gob, hence the types of the
+// events and the snapshots must be registered with gob.Register.
+type GobCodec struct{}
+
+func (GobCodec) Encode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Decode(data []byte) (any, error) {
+	var v any
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Config holds the configuration of the persistence middleware.
+type Config struct {
+	journal       Journal
+	snapshots     SnapshotStore
+	snapshotEvery uint64
+	codec         Codec
+}
+
+// NewConfig returns a new Config that persists the events in the given
+// Journal, encoded with the GobCodec, without snapshots.
+func NewConfig(journal Journal) Config {
+	return Config{
+		journal: journal,
+		codec:   GobCodec{},
+	}
+}
+
+// WithSnapshots saves a snapshot in the given store every given number of
+// persisted events, which bounds the number of events replayed on recovery.
+func (c Config) WithSnapshots(store SnapshotStore, every uint64) Config {
+	c.snapshots = store
+	c.snapshotEvery = every
+	return c
+}
+
+// WithCodec sets the codec of the events and the snapshots.
+func (c Config) WithCodec(codec Codec) Config {
+	c.codec = codec
+	return c
+}
+
+// PersistentActor is a Receiver that recovers its state from its events. It
+// is implemented by embedding Persistent:
+//
+//	type Player struct {
+//		persistence.Persistent
+//		health int
+//	}
+//
+//	func (p *Player) Receive(c *actor.Context) {
+//		switch msg := c.Message().(type) {
+//		case TakeDamage:
+//			p.Persist(Damaged{Amount: msg.Amount}, nil)
+//		}
+//	}
+//
+//	func (p *Player) ApplyEvent(event any) {
+//		switch ev := event.(type) {
+//		case Damaged:
+//			p.health -= ev.Amount
+//		}
+//	}
+type PersistentActor interface {
+	actor.Receiver
+	// ApplyEvent changes the state of the actor with the given event. It is
+	// called for each persisted event and for each event replayed on
+	// recovery.
+	ApplyEvent(event any)
+	// Snapshot returns the state of the actor, which is saved every N
+	// events when snapshots are configured.
+	Snapshot() any
+	// RestoreSnapshot replaces the state of the actor with the given
+	// snapshot, before the events persisted afterwards are replayed.
+	RestoreSnapshot(snapshot any)
+
+	persistent() *Persistent
+}
+
+// Persistent implements the persistence of a PersistentActor, it is meant to
+// be embedded. Its methods must only be called from the goroutine of the
+// actor, while it receives a message.
+type Persistent struct {
+	config   Config
+	id       string
+	seq      uint64
+	receiver PersistentActor
+}
+
+func (p *Persistent) persistent() *Persistent { return p }
+
+// Persist appends the given event to the journal, applies it to the state of
+// the actor with ApplyEvent and calls fn, if not nil, for the side effects
+// like responding. Persist panics when the event cannot be appended, which
+// restarts the actor so it recovers the state of the journal.
+func (p *Persistent) Persist(event any, fn func()) {
+	if p.receiver == nil {
+		panic(ErrNotRecovered)
+	}
+	data, err := p.config.codec.Encode(event)
+	if err != nil {
+		panic(fmt.Errorf("persistence: encode event %T: %w", event, err))
+	}
+	if err := p.config.journal.Append(p.id, p.seq+1, data); err != nil {
+		panic(fmt.Errorf("persistence: append event %T: %w", event, err))
+	}
+	p.seq++
+	p.receiver.ApplyEvent(event)
+	if fn != nil {
+		fn()
+	}
+	if p.config.snapshots != nil && p.config.snapshotEvery > 0 && p.seq%p.config.snapshotEvery == 0 {
+		p.snapshot()
+	}
+}
+
+// LastSequence returns the sequence number of the last persisted event.
+func (p *Persistent) LastSequence() uint64 {
+	return p.seq
+}
+
+// snapshot saves a snapshot, a failure is not fatal since the events are in
+// the journal, hence it only delays the next snapshot.
+func (p *Persistent) snapshot() {
+	data, err := p.config.codec.Encode(p.receiver.Snapshot())
+	if err == nil {
+		err = p.config.snapshots.Save(p.id, p.seq, data)
+	}
+	if err != nil {
+		slog.Error("persistence: snapshot failed", "id", p.id, "seq", p.seq, "err", err)
+	}
+}
+
+// recover restores the state of the actor from the latest snapshot and the
+// events persisted afterwards.
+func (p *Persistent) recover(config Config, id string, receiver PersistentActor) error {
+	p.config, p.id, p.seq = config, id, 0
+	if config.snapshots != nil {
+		snapshot, ok, err := config.snapshots.Load(id)
+		if err != nil {
+			return fmt.Errorf("persistence: load snapshot: %w", err)
+		}
+		if ok {
+			state, err := config.codec.Decode(snapshot.Data)
+			if err != nil {
+				return fmt.Errorf("persistence: decode snapshot: %w", err)
+			}
+			receiver.RestoreSnapshot(state)
+			p.seq = snapshot.Sequence
+		}
+	}
+	err := config.journal.Replay(id, p.seq+1, func(seq uint64, data []byte) error {
+		event, err := config.codec.Decode(data)
+		if err != nil {
+			return fmt.Errorf("persistence: decode event %d: %w", seq, err)
+		}
+		receiver.ApplyEvent(event)
+		p.seq = seq
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	p.receiver = receiver
+	return nil
+}
+
+// Middleware returns the middleware that recovers the state of persistent
+// actors on actor.Initialized, before the actor receives it. The events are
+// persisted under the ID of the PID of the actor, hence an actor that is
+// spawned again with the same kind and ID recovers the same state. Receivers
+// that are not a PersistentActor are left untouched.
+func Middleware(config Config) actor.MiddlewareFunc {
+	return func(next actor.ReceiveFunc) actor.ReceiveFunc {
+		return func(c *actor.Context) {
+			if _, ok := c.Message().(actor.Initialized); ok {
+				if receiver, ok := c.Receiver().(PersistentActor); ok {
+					if err := receiver.persistent().recover(config, c.PID().ID, receiver); err != nil {
+						panic(err)
+					}
+				}
+			}
+			next(c)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package persistence
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anthdm/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type added struct {
+	N int
+}
+
+type counterState struct {
+	Total int
+}
+
+type add struct {
+	n     int
+	crash bool
+}
+
+type getTotal struct{}
+
+func init() {
+	gob.Register(added{})
+	gob.Register(counterState{})
+}
+
+type counter struct {
+	Persistent
+	total   int
+	applied int
+}
+
+func newCounter() actor.Receiver {
+	return &counter{}
+}
+
+func (c *counter) Receive(ctx *actor.Context) {
+	switch msg := ctx.Message().(type) {
+	case add:
+		c.Persist(added{N: msg.n}, func() {
+			if msg.crash {
+				panic("crash after persisting")
+			}
+		})
+	case getTotal:
+		ctx.Respond([2]int{c.total, c.applied})
+	}
+}
+
+func (c *counter) ApplyEvent(event any) {
+	if ev, ok := event.(added); ok {
+		c.total += ev.N
+		c.applied++
+	}
+}
+
+func (c *counter) Snapshot() any {
+	return counterState{Total: c.total}
+}
+
+func (c *counter) RestoreSnapshot(snapshot any) {
+	c.total = snapshot.(counterState).Total
+}
+
+func total(t *testing.T, e *actor.Engine, pid *actor.PID) (int, int) {
+	resp, err := e.Request(pid, getTotal{}, time.Second).Result()
+	require.NoError(t, err)
+	res := resp.([2]int)
+	return res[0], res[1]
+}
+
+func TestPersistentActorRecovers(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	config := NewConfig(NewMemoryJournal())
+	opts := []actor.OptFunc{
+		actor.WithID("1"),
+		actor.WithMiddleware(Middleware(config)),
+		actor.WithRestartDelay(0),
+	}
+	pid := e.Spawn(newCounter, "counter", opts...)
+	e.Send(pid, add{n: 1})
+	e.Send(pid, add{n: 2, crash: true})
+	e.Send(pid, add{n: 3})
+	// the crash restarted the actor, which replayed the first two events.
+	sum, _ := total(t, e, pid)
+	assert.Equal(t, 6, sum)
+
+	<-e.Poison(pid).Done()
+	pid = e.Spawn(newCounter, "counter", opts...)
+	sum, applied := total(t, e, pid)
+	assert.Equal(t, 6, sum)
+	assert.Equal(t, 3, applied)
+}
+
+func TestPersistentActorSnapshots(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	snapshots := NewMemorySnapshotStore()
+	config := NewConfig(NewMemoryJournal()).WithSnapshots(snapshots, 2)
+	opts := []actor.OptFunc{actor.WithID("1"), actor.WithMiddleware(Middleware(config))}
+	pid := e.Spawn(newCounter, "counter", opts...)
+	for i := 1; i <= 5; i++ {
+		e.Send(pid, add{n: i})
+	}
+	total(t, e, pid)
+	snapshot, ok, err := snapshots.Load(pid.ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, uint64(4), snapshot.Sequence)
+
+	<-e.Poison(pid).Done()
+	pid = e.Spawn(newCounter, "counter", opts...)
+	sum, applied := total(t, e, pid)
+	assert.Equal(t, 15, sum)
+	// only the event after the snapshot was replayed.
+	assert.Equal(t, 1, applied)
+}
+
+func TestPersistBeforeRecovery(t *testing.T) {
+	c := &counter{}
+	assert.PanicsWithValue(t, ErrNotRecovered, func() {
+		c.Persist(added{N: 1}, nil)
+	})
+}
+
+func testJournal(t *testing.T, j Journal) {
+	require.NoError(t, j.Append("a", 1, []byte("one")))
+	require.NoError(t, j.Append("b", 1, []byte("other")))
+	require.NoError(t, j.Append("a", 2, []byte("two")))
+	require.NoError(t, j.Append("a", 3, []byte("three")))
+
+	var replayed []string
+	require.NoError(t, j.Replay("a", 2, func(seq uint64, data []byte) error {
+		replayed = append(replayed, string(data))
+		return nil
+	}))
+	assert.Equal(t, []string{"two", "three"}, replayed)
+	require.NoError(t, j.Replay("missing", 1, func(uint64, []byte) error {
+		t.Fatal("replayed an event of a missing id")
+		return nil
+	}))
+}
+
+func TestMemoryJournal(t *testing.T) {
+	testJournal(t, NewMemoryJournal())
+}
+
+func TestFileJournal(t *testing.T) {
+	dir := t.TempDir()
+	j, err := NewFileJournal(dir)
+	require.NoError(t, err)
+	testJournal(t, j)
+	require.NoError(t, j.Close())
+
+	// simulate a crash in the middle of an append.
+	path := filepath.Join(dir, "a.journal")
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 9, 0})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	j, err = NewFileJournal(dir)
+	require.NoError(t, err)
+	defer j.Close()
+	require.NoError(t, j.Append("a", 4, []byte("four")))
+	var seqs []uint64
+	require.NoError(t, j.Replay("a", 1, func(seq uint64, _ []byte) error {
+		seqs = append(seqs, seq)
+		return nil
+	}))
+	assert.Equal(t, []uint64{1, 2, 3, 4}, seqs)
+}
+
+func TestFileJournalCorruptedRecord(t *testing.T) {
+	dir := t.TempDir()
+	j, err := NewFileJournal(dir)
+	require.NoError(t, err)
+	for i, data := range []string{"one", "two", "three"} {
+		require.NoError(t, j.Append("a", uint64(i+1), []byte(data)))
+	}
+	require.NoError(t, j.Close())
+
+	// flip a byte in the data of the second record, keeping its length.
+	path := filepath.Join(dir, "a.journal")
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	b[2*fileJournalHeaderSize+len("one")] ^= 0xff
+	require.NoError(t, os.WriteFile(path, b, 0o644))
+
+	j, err = NewFileJournal(dir)
+	require.NoError(t, err)
+	defer j.Close()
+	var replayed []string
+	replay := func(_ uint64, data []byte) error {
+		replayed = append(replayed, string(data))
+		return nil
+	}
+	require.NoError(t, j.Replay("a", 1, replay))
+	assert.Equal(t, []string{"one"}, replayed)
+
+	// the journal is truncated at the corrupted record.
+	require.NoError(t, j.Append("a", 2, []byte("deux")))
+	replayed = nil
+	require.NoError(t, j.Replay("a", 1, replay))
+	assert.Equal(t, []string{"one", "deux"}, replayed)
+}
+
+func testSnapshotStore(t *testing.T, s SnapshotStore) {
+	_, ok, err := s.Load("player/1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.NoError(t, s.Save("player/1", 10, []byte("ten")))
+	require.NoError(t, s.Save("player/1", 20, []byte("twenty")))
+	snapshot, ok, err := s.Load("player/1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, Snapshot{Sequence: 20, Data: []byte("twenty")}, snapshot)
+}
+
+func TestMemorySnapshotStore(t *testing.T) {
+	testSnapshotStore(t, NewMemorySnapshotStore())
+}
+
+func TestFileSnapshotStore(t *testing.T) {
+	s, err := NewFileSnapshotStore(t.TempDir())
+	require.NoError(t, err)
+	testSnapshotStore(t, s)
+}
@@ -0,0 +1,109 @@
+package persistence
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Snapshot is the state of a persistent actor after the event with the given
+// sequence number.
+type Snapshot struct {
+	Sequence uint64
+	Data     []byte
+}
+
+// SnapshotStore stores the latest snapshot of persistent actors, identified
+// by their persistence ID.
+type SnapshotStore interface {
+	// Save replaces the snapshot of the given ID.
+	Save(id string, seq uint64, data []byte) error
+	// Load returns the snapshot of the given ID, ok is false when there is
+	// none.
+	Load(id string) (snapshot Snapshot, ok bool, err error)
+}
+
+// MemorySnapshotStore is a SnapshotStore that keeps the snapshots in memory.
+type MemorySnapshotStore struct {
+	mu        sync.RWMutex
+	snapshots map[string]Snapshot
+}
+
+// NewMemorySnapshotStore returns a new empty MemorySnapshotStore.
+func NewMemorySnapshotStore() *MemorySnapshotStore {
+	return &MemorySnapshotStore{snapshots: make(map[string]Snapshot)}
+}
+
+func (s *MemorySnapshotStore) Save(id string, seq uint64, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots[id] = Snapshot{Sequence: seq, Data: append([]byte(nil), data...)}
+	return nil
+}
+
+func (s *MemorySnapshotStore) Load(id string) (Snapshot, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot, ok := s.snapshots[id]
+	return snapshot, ok, nil
+}
+
+// FileSnapshotStore is a SnapshotStore that keeps one file per persistence ID
+// in a directory. Snapshots are written to a temporary file first, which
+// replaces the previous snapshot once it is synced, hence a crash never
+// leaves a partial snapshot behind.
+type FileSnapshotStore struct {
+	dir string
+}
+
+// NewFileSnapshotStore returns a FileSnapshotStore that stores the snapshots
+// in the given directory, which is created if it does not exist.
+func NewFileSnapshotStore(dir string) (*FileSnapshotStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileSnapshotStore{dir: dir}, nil
+}
+
+func (s *FileSnapshotStore) Save(id string, seq uint64, data []byte) error {
+	f, err := os.CreateTemp(s.dir, "snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	header := binary.BigEndian.AppendUint64(nil, seq)
+	if _, err := f.Write(append(header, data...)); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path(id))
+}
+
+func (s *FileSnapshotStore) Load(id string) (Snapshot, bool, error) {
+	b, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Snapshot{}, false, nil
+	}
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+	if len(b) < 8 {
+		return Snapshot{}, false, fmt.Errorf("snapshot of %q is corrupt", id)
+	}
+	return Snapshot{Sequence: binary.BigEndian.Uint64(b), Data: b[8:]}, true, nil
+}
+
+func (s *FileSnapshotStore) path(id string) string {
+	return filepath.Join(s.dir, url.PathEscape(id)+".snapshot")
+}
@@ -20,6 +20,7 @@ import (
 type Config struct {
 	TLSConfig *tls.Config
 	BuffSize  int
+	Observer  Observer
 	// Wg        *sync.WaitGroup
 }
 
@@ -43,6 +44,23 @@ func (c Config) WithBufferSize(size int) Config {
 	return c
 }
 
+// WithObserver sets the Observer that is notified about the traffic of the
+// remote.
+func (c Config) WithObserver(o Observer) Config {
+	c.Observer = o
+	return c
+}
+
+// Observer is notified about the messages the remote sends to and receives
+// from its peers, identified by their listen address. Received messages are
+// attributed to the address of their sender, which is empty for messages
+// without a sender. The bytes are the size of the serialized messages. It is
+// called on the hot path, hence it must not block.
+type Observer interface {
+	Sent(peer string, msgs int, bytes int)
+	Received(peer string, msgs int, bytes int)
+}
+
 type Remote struct {
 	addr            string
 	engine          *actor.Engine
@@ -103,7 +121,7 @@ func (r *Remote) Start(e *actor.Engine) error {
 	})
 
 	r.streamRouterPID = r.engine.Spawn(
-		newStreamRouter(r.engine, r.config.TLSConfig, r.config.BuffSize),
+		newStreamRouter(r.engine, r.config),
 		"router", actor.WithInboxSize(1024*1024))
 	slog.Debug("server started", "listenAddr", r.addr)
 	r.stopWg = &sync.WaitGroup{}
@@ -143,10 +161,19 @@ func (r *Remote) Stop() *sync.WaitGroup {
 // message.
 // Sending will work even if the remote is stopped. Receiving however, will not work.
 func (r *Remote) Send(pid *actor.PID, msg any, sender *actor.PID) {
+	r.SendEnvelope(pid, actor.Envelope{Msg: msg, Sender: sender})
+}
+
+// SendEnvelope sends the message of the given Envelope to the process with the
+// given pid over the network, together with its sender, its SpanContext and
+// its headers.
+func (r *Remote) SendEnvelope(pid *actor.PID, env actor.Envelope) {
 	r.engine.Send(r.streamRouterPID, &streamDeliver{
-		target: pid,
-		sender: sender,
-		msg:    msg,
+		target:  pid,
+		sender:  env.Sender,
+		msg:     env.Msg,
+		trace:   env.Trace,
+		headers: env.Headers,
 	})
 }
 
@@ -97,6 +97,30 @@ func TestSend(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestSendEnvelope(t *testing.T) {
+	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
+	defer ra.Stop()
+	assert.NoError(t, err)
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	defer rb.Stop()
+	assert.NoError(t, err)
+	var (
+		trace   = actor.SpanContext{TraceID: actor.TraceID{1}, SpanID: actor.SpanID{2}}
+		headers = map[string]string{"tenant": "acme", "correlation-id": "42"}
+	)
+	received := make(chan actor.Envelope, 1)
+	pid := a.SpawnFunc(func(c *actor.Context) {
+		if msg, ok := c.Message().(*TestMessage); ok {
+			received <- actor.Envelope{Msg: msg, Trace: c.SpanContext(), Headers: c.Headers()}
+		}
+	}, "dfoo")
+
+	b.SendEnvelope(pid, actor.Envelope{Msg: &TestMessage{Data: []byte("foo")}, Trace: trace, Headers: headers})
+	env := <-received
+	assert.Equal(t, trace, env.Trace)
+	assert.Equal(t, headers, env.Headers)
+}
+
 func TestWithSender(t *testing.T) {
 	a, ra, err := makeRemoteEngine(getRandomLocalhostAddr())
 	defer ra.Stop()
@@ -246,6 +270,53 @@ func TestStreamWriterRemoteUnreachableEvent(t *testing.T) {
 	wg.Wait()
 }
 
+func TestStreamWriterIdleTimeout(t *testing.T) {
+	clock := actor.NewVirtualClock(time.Unix(0, 0))
+	aAddr := getRandomLocalhostAddr()
+	ra := New(aAddr, NewConfig())
+	a, err := actor.NewEngine(actor.NewEngineConfig().WithRemote(ra).WithClock(clock))
+	require.NoError(t, err)
+	b, rb, err := makeRemoteEngine(getRandomLocalhostAddr())
+	require.NoError(t, err)
+	defer rb.Stop()
+	defer ra.Stop()
+
+	received := make(chan struct{}, 1)
+	bPID := b.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(*TestMessage); ok {
+			received <- struct{}{}
+		}
+	}, "receiver")
+	unreachable := make(chan struct{}, 1)
+	a.SpawnFunc(func(c *actor.Context) {
+		switch c.Message().(type) {
+		case actor.Started:
+			c.Engine().Subscribe(c.PID())
+		case actor.RemoteUnreachableEvent:
+			unreachable <- struct{}{}
+		}
+	}, "listener")
+
+	a.Send(bPID, &TestMessage{Data: []byte("test")})
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("message was not received")
+	}
+	clock.Advance(connIdleTimeout - time.Second)
+	select {
+	case <-unreachable:
+		t.Fatal("connection closed before its idle timeout")
+	case <-time.After(time.Millisecond * 50):
+	}
+	clock.Advance(time.Second)
+	select {
+	case <-unreachable:
+	case <-time.After(time.Second):
+		t.Fatal("idle connection was not closed")
+	}
+}
+
 func makeRemoteEngine(listenAddr string) (*actor.Engine, *Remote, error) {
 	var e *actor.Engine
 	r := New(listenAddr, NewConfig())
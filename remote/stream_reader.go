@@ -48,9 +48,26 @@ func (r *streamReader) Receive(stream DRPCRemote_ReceiveStream) error {
 			if len(envelope.Senders) > 0 {
 				sender = envelope.Senders[msg.SenderIndex]
 			}
-			r.remote.engine.SendLocal(target, payload, sender)
+			r.remote.engine.SendLocalEnvelope(target, actor.Envelope{
+				Msg:     payload,
+				Sender:  sender,
+				Trace:   spanContext(msg.Trace),
+				Headers: msg.Headers,
+			})
+			if observer := r.remote.config.Observer; observer != nil {
+				observer.Received(sender.GetAddress(), 1, len(msg.Data))
+			}
 		}
 	}
 
 	return nil
 }
+
+func spanContext(tc *TraceContext) actor.SpanContext {
+	var sc actor.SpanContext
+	if tc != nil {
+		copy(sc.TraceID[:], tc.TraceID)
+		copy(sc.SpanID[:], tc.SpanID)
+	}
+	return sc
+}
@@ -8,9 +8,11 @@ import (
 )
 
 type streamDeliver struct {
-	sender *actor.PID
-	target *actor.PID
-	msg    any
+	sender  *actor.PID
+	target  *actor.PID
+	msg     any
+	trace   actor.SpanContext
+	headers map[string]string
 }
 
 type streamRouter struct {
@@ -20,15 +22,17 @@ type streamRouter struct {
 	pid       *actor.PID
 	tlsConfig *tls.Config
 	buffSize  int
+	observer  Observer
 }
 
-func newStreamRouter(e *actor.Engine, tlsConfig *tls.Config, buffSize int) actor.Producer {
+func newStreamRouter(e *actor.Engine, config Config) actor.Producer {
 	return func() actor.Receiver {
 		return &streamRouter{
 			streams:   make(map[string]*actor.PID),
 			engine:    e,
-			tlsConfig: tlsConfig,
-			buffSize:  buffSize,
+			tlsConfig: config.TLSConfig,
+			buffSize:  config.BuffSize,
+			observer:  config.Observer,
 		}
 	}
 }
@@ -62,7 +66,7 @@ func (s *streamRouter) deliverStream(msg *streamDeliver) {
 
 	swpid, ok = s.streams[address]
 	if !ok {
-		swpid = s.engine.SpawnProc(newStreamWriter(s.engine, s.pid, address, s.tlsConfig, s.buffSize))
+		swpid = s.engine.SpawnProc(newStreamWriter(s.engine, s.pid, address, s.tlsConfig, s.buffSize, s.observer))
 		s.streams[address] = swpid
 	}
 
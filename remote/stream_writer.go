@@ -32,9 +32,12 @@ type streamWriter struct {
 	serializer  Serializer
 	tlsConfig   *tls.Config
 	buffSize    int
+	observer    Observer
+	// idle closes the connection once nothing was sent for connIdleTimeout.
+	idle actor.ClockTimer
 }
 
-func newStreamWriter(e *actor.Engine, rpid *actor.PID, address string, tlsConfig *tls.Config, buffSize int) actor.Processer {
+func newStreamWriter(e *actor.Engine, rpid *actor.PID, address string, tlsConfig *tls.Config, buffSize int, observer Observer) actor.Processer {
 	return &streamWriter{
 		writeToAddr: address,
 		engine:      e,
@@ -44,6 +47,7 @@ func newStreamWriter(e *actor.Engine, rpid *actor.PID, address string, tlsConfig
 		serializer:  ProtoSerializer{},
 		tlsConfig:   tlsConfig,
 		buffSize:    buffSize,
+		observer:    observer,
 	}
 }
 
@@ -61,6 +65,7 @@ func (s *streamWriter) Invoke(msgs []actor.Envelope) {
 		targetLookup = make(map[uint64]int32)
 		targets      = make([]*actor.PID, 0)
 		messages     = make([]*Message, len(msgs))
+		sent, size   = 0, 0
 	)
 
 	for i := 0; i < len(msgs); i++ {
@@ -79,12 +84,15 @@ func (s *streamWriter) Invoke(msgs []actor.Envelope) {
 			slog.Error("serialize", "err", err)
 			continue
 		}
+		sent, size = sent+1, size+len(b)
 
 		messages[i] = &Message{
 			Data:          b,
 			TypeNameIndex: typeID,
 			SenderIndex:   senderID,
 			TargetIndex:   targetID,
+			Trace:         traceContext(stream.trace),
+			Headers:       stream.headers,
 		}
 	}
 
@@ -103,12 +111,11 @@ func (s *streamWriter) Invoke(msgs []actor.Envelope) {
 		slog.Error("stream writer failed sending message",
 			"err", err,
 		)
+	} else if s.observer != nil {
+		s.observer.Sent(s.writeToAddr, sent, size)
 	}
-	// refresh the connection deadline.
-	err := s.rawconn.SetDeadline(time.Now().Add(connIdleTimeout))
-	if err != nil {
-		slog.Error("failed to set context deadline", "err", err)
-	}
+	// refresh the idle timeout of the connection.
+	s.idle.Reset(connIdleTimeout)
 }
 
 func (s *streamWriter) init() {
@@ -149,11 +156,9 @@ func (s *streamWriter) init() {
 	}
 
 	s.rawconn = rawconn
-	err = rawconn.SetDeadline(time.Now().Add(connIdleTimeout))
-	if err != nil {
-		slog.Error("failed to set deadline on raw connection", "err", err)
-		return
-	}
+	s.idle = s.engine.Clock().AfterFunc(connIdleTimeout, func() {
+		_ = rawconn.Close()
+	})
 
 	conn := drpcconn.NewWithOptions(rawconn, drpcconn.Options{
 		Manager: drpcmanager.Options{
@@ -196,6 +201,9 @@ func (s *streamWriter) Shutdown() {
 	if s.stream != nil {
 		s.stream.Close()
 	}
+	if s.idle != nil {
+		s.idle.Stop()
+	}
 	s.inbox.Stop()
 	s.engine.Registry.Remove(s.PID())
 }
@@ -205,6 +213,13 @@ func (s *streamWriter) Start() {
 	s.init()
 }
 
+func traceContext(sc actor.SpanContext) *TraceContext {
+	if !sc.IsValid() {
+		return nil
+	}
+	return &TraceContext{TraceID: sc.TraceID[:], SpanID: sc.SpanID[:]}
+}
+
 func lookupPIDs(m map[uint64]int32, pid *actor.PID, pids []*actor.PID) (int32, []*actor.PID) {
 	if pid == nil {
 		return 0, pids
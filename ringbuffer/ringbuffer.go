@@ -30,6 +30,41 @@ func New[T any](size int64) *RingBuffer[T] {
 
 func (rb *RingBuffer[T]) Push(item T) {
 	rb.mu.Lock()
+	rb.push(item)
+	rb.mu.Unlock()
+}
+
+// TryPush pushes the item only when the buffer holds less than max items.
+// It returns false when the item was not pushed.
+func (rb *RingBuffer[T]) TryPush(item T, max int64) bool {
+	rb.mu.Lock()
+	if rb.len >= max {
+		rb.mu.Unlock()
+		return false
+	}
+	rb.push(item)
+	rb.mu.Unlock()
+	return true
+}
+
+// PushEvict pushes the item and, when the buffer already holds max items,
+// drops the oldest item to make room for it. The dropped item is returned.
+func (rb *RingBuffer[T]) PushEvict(item T, max int64) (T, bool) {
+	rb.mu.Lock()
+	var (
+		evicted T
+		ok      bool
+	)
+	if rb.len >= max {
+		evicted, ok = rb.pop()
+	}
+	rb.push(item)
+	rb.mu.Unlock()
+	return evicted, ok
+}
+
+// push must be called with the lock held.
+func (rb *RingBuffer[T]) push(item T) {
 	rb.content.tail = (rb.content.tail + 1) % rb.content.mod
 	if rb.content.tail == rb.content.head {
 		size := rb.content.mod * 2
@@ -48,7 +83,6 @@ func (rb *RingBuffer[T]) Push(item T) {
 	}
 	atomic.AddInt64(&rb.len, 1)
 	rb.content.items[rb.content.tail] = item
-	rb.mu.Unlock()
 }
 
 func (rb *RingBuffer[T]) Len() int64 {
@@ -57,8 +91,14 @@ func (rb *RingBuffer[T]) Len() int64 {
 
 func (rb *RingBuffer[T]) Pop() (T, bool) {
 	rb.mu.Lock()
+	item, ok := rb.pop()
+	rb.mu.Unlock()
+	return item, ok
+}
+
+// pop must be called with the lock held.
+func (rb *RingBuffer[T]) pop() (T, bool) {
 	if rb.len == 0 {
-		rb.mu.Unlock()
 		var t T
 		return t, false
 	}
@@ -67,7 +107,6 @@ func (rb *RingBuffer[T]) Pop() (T, bool) {
 	var t T
 	rb.content.items[rb.content.head] = t
 	atomic.AddInt64(&rb.len, -1)
-	rb.mu.Unlock()
 	return item, true
 }
 
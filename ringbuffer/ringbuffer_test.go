@@ -93,3 +93,41 @@ func TestPopThreadSafety(t *testing.T) {
 		}
 	})
 }
+
+func TestTryPush(t *testing.T) {
+	rb := New[Item](2)
+	for i := 0; i < 4; i++ {
+		if !rb.TryPush(Item{i}, 4) {
+			t.Fatal("expected item to be pushed")
+		}
+	}
+	if rb.TryPush(Item{4}, 4) {
+		t.Fatal("expected item to be rejected")
+	}
+	if rb.Len() != 4 {
+		t.Fatalf("expected len 4, got %d", rb.Len())
+	}
+	item, _ := rb.Pop()
+	if item.i != 0 {
+		t.Fatal("invalid item popped")
+	}
+}
+
+func TestPushEvict(t *testing.T) {
+	rb := New[Item](2)
+	for i := 0; i < 3; i++ {
+		if _, ok := rb.PushEvict(Item{i}, 3); ok {
+			t.Fatal("expected no item to be evicted")
+		}
+	}
+	evicted, ok := rb.PushEvict(Item{3}, 3)
+	if !ok || evicted.i != 0 {
+		t.Fatal("expected the oldest item to be evicted")
+	}
+	items, _ := rb.PopN(3)
+	for i, item := range items {
+		if item.i != i+1 {
+			t.Fatal("invalid item popped")
+		}
+	}
+}
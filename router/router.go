@@ -0,0 +1,146 @@
+package router
+
+import (
+	"github.com/anthdm/hollywood/actor"
+)
+
+// AddRoutee adds the given PID to the routees of a router.
+type AddRoutee struct {
+	PID *actor.PID
+}
+
+// RemoveRoutee removes the given PID from the routees of a router. The
+// routee itself is not stopped.
+type RemoveRoutee struct {
+	PID *actor.PID
+}
+
+// GetRoutees asks a router for its routees, it responds with Routees.
+type GetRoutees struct{}
+
+// Routees holds the routees of a router.
+type Routees struct {
+	PIDs []*actor.PID
+}
+
+type pool struct {
+	size     int
+	producer actor.Producer
+	opts     []actor.OptFunc
+}
+
+type router struct {
+	strategy Strategy
+	routees  *actor.PIDSet
+	pool     *pool
+	group    []*actor.PID
+}
+
+// NewPool returns a Producer of a router that spawns size routees of the
+// given Producer as its children, configured with the given opts. Routees
+// that stop are removed from the pool.
+//
+//	pid := e.Spawn(router.NewPool(router.RoundRobin, 5, newWorker), "workers")
+func NewPool(strategy func() Strategy, size int, p actor.Producer, opts ...actor.OptFunc) actor.Producer {
+	return func() actor.Receiver {
+		return &router{
+			strategy: strategy(),
+			routees:  actor.NewPIDSet(),
+			pool: &pool{
+				size:     size,
+				producer: p,
+				opts:     opts,
+			},
+		}
+	}
+}
+
+// NewGroup returns a Producer of a router that routes to the given PIDs,
+// which can be local or remote. Routees that stop are removed from the group.
+func NewGroup(strategy func() Strategy, pids ...*actor.PID) actor.Producer {
+	return func() actor.Receiver {
+		return &router{
+			strategy: strategy(),
+			routees:  actor.NewPIDSet(),
+			group:    pids,
+		}
+	}
+}
+
+func (r *router) Receive(c *actor.Context) {
+	switch msg := c.Message().(type) {
+	case actor.Initialized, actor.Stopped:
+	case actor.Started:
+		r.start(c)
+	case AddRoutee:
+		r.add(c, msg.PID)
+		r.strategy.SetRoutees(r.routees.Values())
+	case RemoveRoutee:
+		if r.routees.Remove(msg.PID) {
+			c.Unwatch(msg.PID)
+			r.strategy.SetRoutees(r.routees.Values())
+		}
+	case actor.Terminated:
+		if r.routees.Remove(msg.PID) {
+			r.strategy.SetRoutees(r.routees.Values())
+		}
+	case GetRoutees:
+		c.Respond(Routees{PIDs: r.routees.Clone().Values()})
+	default:
+		r.route(c, msg)
+	}
+}
+
+func (r *router) start(c *actor.Context) {
+	if r.pool != nil {
+		// When the router restarted, the routees it spawned are still alive.
+		for _, pid := range c.Children() {
+			r.add(c, pid)
+		}
+		for r.routees.Len() < r.pool.size {
+			pid := c.SpawnChild(r.pool.producer, "routee", r.pool.opts...)
+			if pid == nil {
+				break
+			}
+			r.add(c, pid)
+		}
+	}
+	for _, pid := range r.group {
+		r.add(c, pid)
+	}
+	r.strategy.SetRoutees(r.routees.Values())
+}
+
+func (r *router) add(c *actor.Context, pid *actor.PID) {
+	if pid == nil || r.routees.Contains(pid) {
+		return
+	}
+	r.routees.Add(pid)
+	c.Watch(pid)
+}
+
+// route sends the message to the routees picked by the strategy, keeping the
+// original sender so routees can respond to it, and the headers and the
+// SpanContext of the message.
+func (r *router) route(c *actor.Context, msg any) {
+	pids := r.strategy.Route(c.Engine(), msg)
+	if len(pids) == 0 {
+		c.Engine().BroadcastEvent(actor.DeadLetterEvent{
+			Target:  c.PID(),
+			Message: msg,
+			Sender:  c.Sender(),
+			Headers: c.Headers(),
+			Trace:   c.SpanContext(),
+		})
+		return
+	}
+	env := actor.Envelope{
+		Msg:     msg,
+		Sender:  c.Sender(),
+		Trace:   c.SpanContext(),
+		Headers: c.Headers(),
+	}
+	for _, pid := range pids {
+		c.Engine().SendEnvelope(pid, env)
+	}
+}
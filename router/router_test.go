@@ -0,0 +1,214 @@
+package router
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthdm/hollywood/actor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type funcReceiver func(*actor.Context)
+
+func (f funcReceiver) Receive(c *actor.Context) { f(c) }
+
+func newFuncReceiver(f func(*actor.Context)) actor.Producer {
+	return func() actor.Receiver { return funcReceiver(f) }
+}
+
+type keyed string
+
+func (k keyed) HashKey() string { return string(k) }
+
+// recorder counts the messages received per routee.
+type recorder struct {
+	mu   sync.Mutex
+	wg   sync.WaitGroup
+	msgs map[string][]any
+}
+
+func newRecorder(n int) *recorder {
+	r := &recorder{msgs: make(map[string][]any)}
+	r.wg.Add(n)
+	return r
+}
+
+func (r *recorder) receive(c *actor.Context) {
+	switch msg := c.Message().(type) {
+	case actor.Initialized, actor.Started, actor.Stopped:
+	default:
+		r.mu.Lock()
+		r.msgs[c.PID().ID] = append(r.msgs[c.PID().ID], msg)
+		r.mu.Unlock()
+		r.wg.Done()
+	}
+}
+
+func (r *recorder) counts() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[string]int)
+	for id, msgs := range r.msgs {
+		counts[id] = len(msgs)
+	}
+	return counts
+}
+
+func routees(t *testing.T, e *actor.Engine, pid *actor.PID) []*actor.PID {
+	resp, err := e.Request(pid, GetRoutees{}, time.Second).Result()
+	require.NoError(t, err)
+	return resp.(Routees).PIDs
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	rec := newRecorder(9)
+	pid := e.Spawn(NewPool(RoundRobin, 3, newFuncReceiver(rec.receive)), "pool")
+	for i := 0; i < 9; i++ {
+		e.Send(pid, i)
+	}
+	rec.wg.Wait()
+	counts := rec.counts()
+	require.Len(t, counts, 3)
+	for _, n := range counts {
+		assert.Equal(t, 3, n)
+	}
+}
+
+func TestGroupBroadcast(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	rec := newRecorder(6)
+	var pids []*actor.PID
+	for i := 0; i < 3; i++ {
+		pids = append(pids, e.SpawnFunc(rec.receive, "routee"))
+	}
+	pid := e.Spawn(NewGroup(Broadcast, pids...), "group")
+	e.Send(pid, 1)
+	e.Send(pid, 2)
+	rec.wg.Wait()
+	counts := rec.counts()
+	require.Len(t, counts, 3)
+	for _, n := range counts {
+		assert.Equal(t, 2, n)
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	rec := newRecorder(30)
+	pid := e.Spawn(NewPool(ConsistentHash, 5, newFuncReceiver(rec.receive)), "pool")
+	for i := 0; i < 10; i++ {
+		for _, key := range []keyed{"a", "b", "c"} {
+			e.Send(pid, key)
+		}
+	}
+	rec.wg.Wait()
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	// each key is received by a single routee.
+	owners := make(map[any]string)
+	for id, msgs := range rec.msgs {
+		for _, msg := range msgs {
+			if owner, ok := owners[msg]; ok {
+				assert.Equal(t, owner, id)
+			}
+			owners[msg] = id
+		}
+	}
+	assert.Len(t, owners, 3)
+}
+
+func TestSmallestInbox(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		blocking = make(chan struct{})
+		received = make(chan struct{})
+		done     = make(chan string, 10)
+	)
+	defer close(blocking)
+	busy := e.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(string); ok {
+			received <- struct{}{}
+			<-blocking
+		}
+	}, "busy")
+	free := e.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(int); ok {
+			done <- c.PID().ID
+		}
+	}, "free")
+	e.Send(busy, "block")
+	<-received
+	e.Send(busy, "queued")
+
+	pid := e.Spawn(NewGroup(SmallestInbox, busy, free), "group")
+	e.Send(pid, 1)
+	select {
+	case id := <-done:
+		assert.Equal(t, free.ID, id)
+	case <-time.After(time.Second):
+		t.Fatal("message was not routed to the smallest inbox")
+	}
+}
+
+func TestAddRemoveRoutee(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	var (
+		a   = e.SpawnFunc(func(c *actor.Context) {}, "routee")
+		b   = e.SpawnFunc(func(c *actor.Context) {}, "routee")
+		pid = e.Spawn(NewGroup(RoundRobin, a), "group")
+	)
+	e.Send(pid, AddRoutee{PID: b})
+	assert.Len(t, routees(t, e, pid), 2)
+
+	e.Send(pid, RemoveRoutee{PID: a})
+	pids := routees(t, e, pid)
+	require.Len(t, pids, 1)
+	assert.True(t, pids[0].Equals(b))
+
+	// stopped routees are removed.
+	<-e.Poison(b).Done()
+	assert.Eventually(t, func() bool {
+		return len(routees(t, e, pid)) == 0
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestRouteKeepsSender(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	pid := e.Spawn(NewPool(Random, 2, newFuncReceiver(func(c *actor.Context) {
+		if msg, ok := c.Message().(string); ok {
+			c.Respond(msg)
+		}
+	})), "pool")
+	resp, err := e.Request(pid, "ping", time.Second).Result()
+	require.NoError(t, err)
+	assert.Equal(t, "ping", resp)
+}
+
+func TestRouteKeepsHeadersAndTrace(t *testing.T) {
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	received := make(chan actor.Envelope, 1)
+	pid := e.Spawn(NewPool(RoundRobin, 2, newFuncReceiver(func(c *actor.Context) {
+		if msg, ok := c.Message().(string); ok {
+			received <- actor.Envelope{Msg: msg, Headers: c.Headers(), Trace: c.SpanContext()}
+		}
+	})), "pool")
+	sc := actor.SpanContext{TraceID: actor.TraceID{1}, SpanID: actor.SpanID{2}}
+	e.SendEnvelope(pid, actor.Envelope{Msg: "ping", Headers: map[string]string{"id": "1"}, Trace: sc})
+	select {
+	case env := <-received:
+		assert.Equal(t, "1", env.Headers["id"])
+		assert.Equal(t, sc, env.Trace)
+	case <-time.After(time.Second):
+		t.Fatal("message was not routed")
+	}
+}
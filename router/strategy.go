@@ -0,0 +1,169 @@
+package router
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"github.com/anthdm/hollywood/actor"
+	"github.com/zeebo/xxh3"
+)
+
+// Strategy picks the routees a message is routed to. Each router creates
+// its own Strategy, hence implementations don't need to be thread safe.
+type Strategy interface {
+	// SetRoutees is called each time the routees of the router changed.
+	SetRoutees(routees []*actor.PID)
+	// Route returns the routees the given message is sent to.
+	Route(e *actor.Engine, msg any) []*actor.PID
+}
+
+// Hasher is implemented by messages that are routed by ConsistentHash.
+// Messages with the same key are always routed to the same routee as long
+// as the routees don't change.
+type Hasher interface {
+	HashKey() string
+}
+
+type roundRobin struct {
+	routees []*actor.PID
+	next    int
+}
+
+// RoundRobin routes each message to the next routee.
+func RoundRobin() Strategy {
+	return &roundRobin{}
+}
+
+func (s *roundRobin) SetRoutees(routees []*actor.PID) {
+	s.routees = routees
+}
+
+func (s *roundRobin) Route(_ *actor.Engine, _ any) []*actor.PID {
+	if len(s.routees) == 0 {
+		return nil
+	}
+	s.next = s.next % len(s.routees)
+	pid := s.routees[s.next]
+	s.next++
+	return []*actor.PID{pid}
+}
+
+type random struct {
+	routees []*actor.PID
+}
+
+// Random routes each message to a random routee.
+func Random() Strategy {
+	return &random{}
+}
+
+func (s *random) SetRoutees(routees []*actor.PID) {
+	s.routees = routees
+}
+
+func (s *random) Route(_ *actor.Engine, _ any) []*actor.PID {
+	if len(s.routees) == 0 {
+		return nil
+	}
+	return []*actor.PID{s.routees[rand.Intn(len(s.routees))]}
+}
+
+type broadcast struct {
+	routees []*actor.PID
+}
+
+// Broadcast routes each message to all the routees.
+func Broadcast() Strategy {
+	return &broadcast{}
+}
+
+func (s *broadcast) SetRoutees(routees []*actor.PID) {
+	s.routees = routees
+}
+
+func (s *broadcast) Route(_ *actor.Engine, _ any) []*actor.PID {
+	return s.routees
+}
+
+type smallestInbox struct {
+	routees []*actor.PID
+}
+
+// SmallestInbox routes each message to the routee with the least messages
+// waiting in its inbox. Remote routees are only picked when none of the
+// local routees is available.
+func SmallestInbox() Strategy {
+	return &smallestInbox{}
+}
+
+func (s *smallestInbox) SetRoutees(routees []*actor.PID) {
+	s.routees = routees
+}
+
+func (s *smallestInbox) Route(e *actor.Engine, _ any) []*actor.PID {
+	var (
+		pick     *actor.PID
+		smallest = math.MaxInt
+	)
+	for _, pid := range s.routees {
+		n, ok := e.InboxLen(pid)
+		if !ok {
+			n = math.MaxInt - 1
+		}
+		if n < smallest {
+			pick, smallest = pid, n
+		}
+		if n == 0 {
+			break
+		}
+	}
+	if pick == nil {
+		return nil
+	}
+	return []*actor.PID{pick}
+}
+
+// replicas is the number of points each routee has on the hash ring, which
+// spreads the keys evenly over the routees.
+const replicas = 100
+
+type consistentHash struct {
+	hashes []uint64
+	ring   map[uint64]*actor.PID
+}
+
+// ConsistentHash routes the messages implementing Hasher by their key.
+// Adding or removing a routee only moves the keys of that routee. Messages
+// that don't implement Hasher are dropped as a DeadLetterEvent.
+func ConsistentHash() Strategy {
+	return &consistentHash{}
+}
+
+func (s *consistentHash) SetRoutees(routees []*actor.PID) {
+	s.hashes = make([]uint64, 0, len(routees)*replicas)
+	s.ring = make(map[uint64]*actor.PID, len(routees)*replicas)
+	for _, pid := range routees {
+		key := pid.String()
+		for i := 0; i < replicas; i++ {
+			h := xxh3.HashString(key + "#" + strconv.Itoa(i))
+			s.hashes = append(s.hashes, h)
+			s.ring[h] = pid
+		}
+	}
+	sort.Slice(s.hashes, func(i, j int) bool { return s.hashes[i] < s.hashes[j] })
+}
+
+func (s *consistentHash) Route(e *actor.Engine, msg any) []*actor.PID {
+	hasher, ok := msg.(Hasher)
+	if !ok || len(s.hashes) == 0 {
+		return nil
+	}
+	h := xxh3.HashString(hasher.HashKey())
+	i := sort.Search(len(s.hashes), func(i int) bool { return s.hashes[i] >= h })
+	if i == len(s.hashes) {
+		i = 0
+	}
+	return []*actor.PID{s.ring[s.hashes[i]]}
+}
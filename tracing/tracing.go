@@ -0,0 +1,124 @@
+// Package tracing follows messages as they cross actors and nodes. The
+// Middleware creates a span per received message, which is the child of the
+// span of the sender, and hands the finished spans to an Exporter.
+package tracing
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/anthdm/hollywood/actor"
+)
+
+// Span is a received message of a trace.
+type Span struct {
+	actor.SpanContext
+	// Parent is the SpanID of the span that sent the message, it is invalid
+	// for the root span of a trace.
+	Parent actor.SpanID
+	// Name is the type of the received message, "<nil>" for a nil message.
+	Name   string
+	PID    *actor.PID
+	Sender *actor.PID
+	Start  time.Time
+	End    time.Time
+	// Panicked is set when the actor panicked receiving the message.
+	Panicked bool
+}
+
+// Duration returns the time the actor spent receiving the message.
+func (s Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Exporter receives the finished spans. ExportSpan is called on the goroutine
+// of the actor, hence it must not block.
+type Exporter interface {
+	ExportSpan(Span)
+}
+
+// NoopExporter drops all the spans. It is useful to propagate the trace
+// context through an engine that does not export spans itself.
+type NoopExporter struct{}
+
+func (NoopExporter) ExportSpan(Span) {}
+
+// InMemoryExporter keeps all the spans in memory, which is meant for tests.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+// NewInMemoryExporter returns a new empty InMemoryExporter.
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) ExportSpan(span Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns the exported spans, in the order they finished.
+func (e *InMemoryExporter) Spans() []Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Span(nil), e.spans...)
+}
+
+// Reset drops all the exported spans.
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// Middleware returns the middleware that creates a span for each message the
+// actor receives, except for the lifecycle messages. Messages without a trace
+// context start a new trace. The messages sent while receiving are children
+// of the span.
+func Middleware(exporter Exporter) actor.MiddlewareFunc {
+	return func(next actor.ReceiveFunc) actor.ReceiveFunc {
+		return func(c *actor.Context) {
+			switch c.Message().(type) {
+			case actor.Initialized, actor.Started, actor.Stopped:
+				next(c)
+				return
+			}
+			parent := c.SpanContext()
+			span := Span{
+				SpanContext: NewSpanContext(),
+				Name:        fmt.Sprintf("%T", c.Message()),
+				PID:         c.PID(),
+				Sender:      c.Sender(),
+				Start:       time.Now(),
+				Panicked:    true,
+			}
+			if parent.IsValid() {
+				span.TraceID = parent.TraceID
+				span.Parent = parent.SpanID
+			}
+			c.SetSpanContext(span.SpanContext)
+			defer func() {
+				span.End = time.Now()
+				exporter.ExportSpan(span)
+			}()
+			next(c)
+			span.Panicked = false
+		}
+	}
+}
+
+// NewSpanContext returns a SpanContext with a new random TraceID and SpanID,
+// which starts a new trace.
+func NewSpanContext() actor.SpanContext {
+	var sc actor.SpanContext
+	binary.BigEndian.PutUint64(sc.TraceID[:8], rand.Uint64())
+	binary.BigEndian.PutUint64(sc.TraceID[8:], rand.Uint64()|1)
+	binary.BigEndian.PutUint64(sc.SpanID[:], rand.Uint64()|1)
+	return sc
+}
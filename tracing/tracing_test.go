@@ -0,0 +1,150 @@
+package tracing
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/anthdm/hollywood/actor"
+	"github.com/anthdm/hollywood/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ping struct{}
+
+func TestMiddlewareChildSpans(t *testing.T) {
+	exp := NewInMemoryExporter()
+	e, err := actor.NewEngine(actor.NewEngineConfig().WithMiddleware(Middleware(exp)))
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	last := e.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(int); ok {
+			c.Respond("pong")
+		}
+	}, "last")
+	middle := e.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(string); ok {
+			c.Request(last, 1, time.Second).OnComplete(func(any, error) { close(done) })
+		}
+	}, "middle")
+	first := e.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(ping); ok {
+			c.Send(middle, "hello")
+		}
+	}, "first")
+	e.Send(first, ping{})
+	<-done
+
+	var spans []Span
+	require.Eventually(t, func() bool {
+		spans = exp.Spans()
+		return len(spans) == 3
+	}, time.Second, time.Millisecond*10)
+	byName := make(map[string]Span)
+	for _, span := range spans {
+		byName[span.Name] = span
+	}
+	root, child, grandChild := byName["tracing.ping"], byName["string"], byName["int"]
+	assert.True(t, root.IsValid())
+	assert.False(t, root.Parent.IsValid())
+	assert.Equal(t, root.TraceID, child.TraceID)
+	assert.Equal(t, root.TraceID, grandChild.TraceID)
+	assert.Equal(t, root.SpanID, child.Parent)
+	assert.Equal(t, child.SpanID, grandChild.Parent)
+	assert.Equal(t, first.ID, child.Sender.ID)
+	assert.False(t, grandChild.Panicked)
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	exp := NewInMemoryExporter()
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	pid := e.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(ping); ok {
+			panic("boom")
+		}
+	}, "crasher", actor.WithMiddleware(Middleware(exp)), actor.WithRestartDelay(0))
+
+	parent := NewSpanContext()
+	e.SendEnvelope(pid, actor.Envelope{Msg: ping{}, Trace: parent})
+	require.Eventually(t, func() bool {
+		return len(exp.Spans()) == 1
+	}, time.Second, time.Millisecond*10)
+	span := exp.Spans()[0]
+	assert.True(t, span.Panicked)
+	assert.Equal(t, parent.TraceID, span.TraceID)
+	assert.Equal(t, parent.SpanID, span.Parent)
+}
+
+func TestRemotePropagation(t *testing.T) {
+	var (
+		exp   = NewInMemoryExporter()
+		addrA = fmt.Sprintf("127.0.0.1:%d", rand.Intn(50000)+10000)
+		addrB = fmt.Sprintf("127.0.0.1:%d", rand.Intn(50000)+10000)
+	)
+	a, err := actor.NewEngine(actor.NewEngineConfig().
+		WithRemote(remote.New(addrA, remote.NewConfig())).
+		WithMiddleware(Middleware(exp)))
+	require.NoError(t, err)
+	b, err := actor.NewEngine(actor.NewEngineConfig().
+		WithRemote(remote.New(addrB, remote.NewConfig())).
+		WithMiddleware(Middleware(exp)))
+	require.NoError(t, err)
+
+	received := make(chan actor.SpanContext, 1)
+	target := b.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(*actor.PID); ok {
+			received <- c.SpanContext()
+		}
+	}, "target")
+	sender := a.SpawnFunc(func(c *actor.Context) {
+		if _, ok := c.Message().(ping); ok {
+			c.Send(target, c.PID())
+		}
+	}, "sender")
+	a.Send(sender, ping{})
+
+	sc := <-received
+	var spans []Span
+	require.Eventually(t, func() bool {
+		spans = exp.Spans()
+		return len(spans) == 2
+	}, time.Second, time.Millisecond*10)
+	root, remoteSpan := spans[0], spans[1]
+	if root.Name != "tracing.ping" {
+		root, remoteSpan = remoteSpan, root
+	}
+	assert.Equal(t, root.TraceID, remoteSpan.TraceID)
+	assert.Equal(t, root.SpanID, remoteSpan.Parent)
+	assert.Equal(t, remoteSpan.SpanContext, sc)
+}
+
+func TestMiddlewareNilMessage(t *testing.T) {
+	exp := NewInMemoryExporter()
+	e, err := actor.NewEngine(actor.NewEngineConfig())
+	require.NoError(t, err)
+	received := make(chan any, 1)
+	pid := e.SpawnFunc(func(c *actor.Context) {
+		switch c.Message().(type) {
+		case actor.Initialized, actor.Started, actor.Stopped:
+		default:
+			received <- c.Message()
+		}
+	}, "nil", actor.WithMiddleware(Middleware(exp)))
+	e.Send(pid, nil)
+	select {
+	case msg := <-received:
+		assert.Nil(t, msg)
+	case <-time.After(time.Second):
+		t.Fatal("nil message not received")
+	}
+	require.Eventually(t, func() bool {
+		return len(exp.Spans()) == 1
+	}, time.Second, time.Millisecond*10)
+	span := exp.Spans()[0]
+	assert.Equal(t, "<nil>", span.Name)
+	assert.False(t, span.Panicked)
+}